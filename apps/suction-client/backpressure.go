@@ -0,0 +1,112 @@
+package main
+
+import (
+	"time"
+
+	"go/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sendRate is the rate limit the server suggested while its ingest pipeline falls behind
+type sendRate struct {
+	load           uint32
+	bytesPerSecond uint64
+}
+
+// apply returns the flush interval to use instead of interval and how many items of bytesPerItem
+// a batch may hold to stay under the rate, 0 for no limit. The interval grows with the load up to
+// three times, as every batch costs the server a frame to decode. Before the first batch the item
+// size is unknown and only the interval changes.
+func (r sendRate) apply(interval time.Duration, bytesPerItem uint64) (time.Duration, int) {
+	if r.bytesPerSecond == 0 {
+		return interval, 0
+	}
+
+	interval += interval * time.Duration(min(r.load, 100)) / 50
+	if bytesPerItem == 0 {
+		return interval, 0
+	}
+
+	items := int(float64(r.bytesPerSecond) * interval.Seconds() / float64(bytesPerItem))
+	if items < 1 {
+		// Not even one item fits in the interval, so single items go out as often as the rate allows
+		return time.Duration(float64(bytesPerItem) / float64(r.bytesPerSecond) * float64(time.Second)), 1
+	}
+
+	return interval, items
+}
+
+// pacingMetrics exposes how fast the client sends
+type pacingMetrics struct {
+	flushInterval prometheus.Gauge
+	batchMaxItems prometheus.Gauge
+	suggestedRate prometheus.Gauge
+	effectiveRate prometheus.Gauge
+	serverLoad    prometheus.Gauge
+	signals       prometheus.Counter
+	deferred      prometheus.Counter
+}
+
+func newPacingMetrics(metrics *common.Metrics) (*pacingMetrics, error) {
+	gauge := func(name, help string) prometheus.Gauge {
+		return prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "client",
+			Name:      name,
+			Help:      help,
+		})
+	}
+
+	pacing := &pacingMetrics{
+		flushInterval: gauge("flush_interval_seconds", "Flush interval in effect."),
+		batchMaxItems: gauge("batch_max_items", "Items a batch may hold under backpressure, 0 without a limit."),
+		suggestedRate: gauge("backpressure_suggested_bytes_per_second", "Send rate suggested by the server, 0 without a limit."),
+		effectiveRate: gauge("effective_send_rate_bytes_per_second", "Bytes per second the flush interval and batch size let through, 0 without a limit."),
+		serverLoad:    gauge("backpressure_server_load_ratio", "Ingest pipeline load last reported by the server, from 0 to 1."),
+		signals: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "client",
+			Name:      "backpressure_signals_total",
+			Help:      "Backpressure messages received from the server.",
+		}),
+		deferred: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "client",
+			Name:      "deferred_batches_total",
+			Help:      "Batches the server refused under its rate limits or quota and that went back to the pool.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{pacing.flushInterval, pacing.batchMaxItems, pacing.suggestedRate, pacing.effectiveRate, pacing.serverLoad, pacing.signals, pacing.deferred} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return pacing, nil
+}
+
+// observe records the pacing in effect
+func (m *pacingMetrics) observe(interval time.Duration, batchMax int, bytesPerItem uint64, rate sendRate) {
+	m.flushInterval.Set(interval.Seconds())
+	m.batchMaxItems.Set(float64(batchMax))
+	m.suggestedRate.Set(float64(rate.bytesPerSecond))
+	m.serverLoad.Set(float64(rate.load) / 100)
+
+	if batchMax == 0 {
+		m.effectiveRate.Set(0)
+		return
+	}
+	m.effectiveRate.Set(float64(uint64(batchMax)*bytesPerItem) / interval.Seconds())
+}
+
+// pace returns the flush interval and batch size, 0 for no limit, in effect under a slow down and
+// a rate suggested by the server
+func (qc *QuicClient) pace(slow slowDown, rate sendRate) (time.Duration, int) {
+	bytesPerItem := qc.bytesPerItem.Load()
+	interval, batchMax := rate.apply(slow.apply(time.Duration(qc.flushInterval.Load())), bytesPerItem)
+	qc.pacing.observe(interval, batchMax, bytesPerItem, rate)
+
+	return interval, batchMax
+}
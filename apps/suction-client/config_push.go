@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// ConfigCache keeps the last configuration pushed by the server in a file, so the next start
+// applies it before connecting
+type ConfigCache struct {
+	path string
+}
+
+// NewConfigCache creates a cache at path. An empty path disables the cache.
+func NewConfigCache(path string) *ConfigCache {
+	return &ConfigCache{path: path}
+}
+
+// Enabled reports whether a cache file is configured
+func (c *ConfigCache) Enabled() bool {
+	return c.path != ""
+}
+
+// Save replaces the cached configuration. The file is written next to the cache and renamed over
+// it, so a crash leaves the previous configuration in place.
+func (c *ConfigCache) Save(config *pb.RemoteConfig) error {
+	data, err := proto.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return errors.New("Failed to write config cache " + c.path + ": " + err.Error())
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return errors.New("Failed to write config cache " + c.path + ": " + err.Error())
+	}
+	if err := file.Close(); err != nil {
+		return errors.New("Failed to write config cache " + c.path + ": " + err.Error())
+	}
+
+	if err := os.Rename(file.Name(), c.path); err != nil {
+		return errors.New("Failed to write config cache " + c.path + ": " + err.Error())
+	}
+
+	return nil
+}
+
+// Load returns the cached configuration, or nil when nothing was cached
+func (c *ConfigCache) Load() (*pb.RemoteConfig, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("Failed to read config cache " + c.path + ": " + err.Error())
+	}
+
+	config := &pb.RemoteConfig{}
+	if err := proto.Unmarshal(data, config); err != nil {
+		return nil, errors.New("Failed to read config cache " + c.path + ": " + err.Error())
+	}
+
+	return config, nil
+}
+
+// clientSettings are the reloadable settings the server can override
+type clientSettings struct {
+	poolMaxItems  int
+	flushInterval time.Duration
+}
+
+// override returns the settings with the non-zero values of a pushed configuration
+func (s clientSettings) override(config *pb.RemoteConfig) clientSettings {
+	if config == nil {
+		return s
+	}
+	if config.PoolMaxItems > 0 {
+		s.poolMaxItems = int(config.PoolMaxItems)
+	}
+	if config.FlushIntervalMs > 0 {
+		s.flushInterval = time.Duration(config.FlushIntervalMs) * time.Millisecond
+	}
+
+	return s
+}
+
+// applySettings applies the local settings with the pushed configuration on top and returns them
+func (qc *QuicClient) applySettings() clientSettings {
+	qc.settingsMu.Lock()
+	defer qc.settingsMu.Unlock()
+
+	settings := qc.local.override(qc.pushed)
+	qc.dataPool.SetMaxItems(settings.poolMaxItems)
+	qc.flushInterval.Store(int64(settings.flushInterval))
+
+	return settings
+}
+
+// restorePushedConfig applies the configuration cached by the previous run
+func (qc *QuicClient) restorePushedConfig() error {
+	if qc.config.Client.ConfigPush.Disabled || !qc.configCache.Enabled() {
+		return nil
+	}
+
+	config, err := qc.configCache.Load()
+	if err != nil || config == nil {
+		return err
+	}
+
+	qc.settingsMu.Lock()
+	qc.pushed = config
+	qc.settingsMu.Unlock()
+
+	settings := qc.applySettings()
+
+	qc.logger.Info("Restored pushed config",
+		zap.Uint64("version", config.Version),
+		zap.String("source", config.Source),
+		zap.Int("pool_max_items", settings.poolMaxItems),
+		zap.Duration("flush_interval", settings.flushInterval))
+
+	return nil
+}
+
+// acceptConfigStreams serves the control streams the server opens on a connection until it closes
+func (qc *QuicClient) acceptConfigStreams(conn *quic.Conn) {
+	for {
+		stream, err := conn.AcceptStream(conn.Context())
+		if err != nil {
+			return
+		}
+
+		go qc.handleConfigStream(conn, stream)
+	}
+}
+
+// handleConfigStream applies every configuration pushed on a control stream and acks its version
+func (qc *QuicClient) handleConfigStream(conn *quic.Conn, stream *quic.Stream) {
+	defer stream.Close()
+
+	reader := common.NewFrameReader(stream, common.FrameHeaderSize, common.ControlFrameMaxSize)
+
+	for {
+		config := &pb.RemoteConfig{}
+		if err := common.ReadMessage(reader, common.FrameConfig, config); err != nil {
+			if _, ok := common.ErrorCodeFromError(err); !ok && !errors.Is(err, io.EOF) && conn.Context().Err() == nil {
+				qc.logger.Warn("Failed to read pushed config", zap.Error(err))
+				stream.CancelRead(quic.StreamErrorCode(common.ErrorCodeProtocolViolation))
+			}
+			return
+		}
+
+		if err := common.WriteMessage(stream, common.FrameConfigAck, qc.applyPushedConfig(config)); err != nil {
+			qc.logger.Debug("Failed to ack pushed config", zap.Error(err))
+			return
+		}
+	}
+}
+
+// applyPushedConfig applies a configuration pushed by the server and keeps it in the cache
+func (qc *QuicClient) applyPushedConfig(config *pb.RemoteConfig) *pb.RemoteConfigAck {
+	ack := &pb.RemoteConfigAck{Version: config.Version}
+
+	if qc.config.Client.ConfigPush.Disabled {
+		ack.Message = "config push disabled"
+		qc.logger.Info("Ignoring pushed config, config push is disabled", zap.Uint64("version", config.Version))
+		return ack
+	}
+
+	qc.settingsMu.Lock()
+	current := qc.pushed
+	if current != nil && current.Version == config.Version && current.Source == config.Source {
+		qc.settingsMu.Unlock()
+		ack.Applied = true
+		return ack
+	}
+	qc.pushed = config
+	qc.settingsMu.Unlock()
+
+	settings := qc.applySettings()
+	ack.Applied = true
+
+	qc.logger.Info("Applied pushed config",
+		zap.Uint64("version", config.Version),
+		zap.String("source", config.Source),
+		zap.Int("pool_max_items", settings.poolMaxItems),
+		zap.Duration("flush_interval", settings.flushInterval))
+
+	if qc.configCache.Enabled() {
+		if err := qc.configCache.Save(config); err != nil {
+			qc.logger.Warn("Pushed config will not survive a restart", zap.Error(err))
+			ack.Message = err.Error()
+		}
+	}
+
+	return ack
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	StrategyPriority   = "priority"
+	StrategyRoundRobin = "round_robin"
+	StrategyLowestRTT  = "lowest_rtt"
+)
+
+// rttWeight is the weight of a new handshake time in the smoothed RTT of an endpoint
+const rttWeight = 0.3
+
+// Endpoint is a server the client can connect to
+type Endpoint struct {
+	Address  string
+	Priority int
+
+	failures     int
+	ejectedUntil time.Time
+	rtt          time.Duration
+}
+
+// Endpoints ranks the servers the client connects to and tracks their health
+type Endpoints struct {
+	mu        sync.Mutex
+	logger    *zap.Logger
+	config    common.ClientEndpoints
+	resolver  *net.Resolver
+	endpoints []*Endpoint
+	resolved  time.Time
+	rotation  int
+	current   string
+	redirect  string
+	healthy   *prometheus.GaugeVec
+	failovers prometheus.Counter
+}
+
+func NewEndpoints(logger *zap.Logger, config *common.Config, metrics *common.Metrics) (*Endpoints, error) {
+	endpoints := &Endpoints{
+		logger:   logger,
+		config:   config.Client.Endpoints,
+		resolver: net.DefaultResolver,
+		healthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "client",
+			Name:      "endpoint_healthy",
+			Help:      "Whether an endpoint is available for connections (1) or ejected (0).",
+		}, []string{"endpoint"}),
+		failovers: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "client",
+			Name:      "endpoint_failovers_total",
+			Help:      "Connections made to a different endpoint than the previous connection.",
+		}),
+	}
+
+	if err := metrics.Registry.Register(endpoints.healthy); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(endpoints.failovers); err != nil {
+		return nil, err
+	}
+
+	if resolver := endpoints.config.Resolver; resolver != "" {
+		endpoints.resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, resolver)
+			},
+		}
+	}
+
+	if endpoints.config.SRV == "" {
+		addresses := endpoints.config.Addresses
+		if len(addresses) == 0 {
+			addresses = []string{config.Client.ConnectionAddress}
+		}
+
+		for i, address := range addresses {
+			endpoints.endpoints = append(endpoints.endpoints, &Endpoint{Address: address, Priority: i})
+			endpoints.healthy.WithLabelValues(address).Set(1)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// Candidates returns the endpoints to try for a connection, in order. Ejected endpoints are left
+// out unless every endpoint is ejected, in which case all of them are tried.
+func (e *Endpoints) Candidates(ctx context.Context) ([]*Endpoint, error) {
+	if e.config.SRV != "" {
+		if err := e.refresh(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	candidates := make([]*Endpoint, 0, len(e.endpoints))
+	for _, endpoint := range e.endpoints {
+		if now.Before(endpoint.ejectedUntil) {
+			continue
+		}
+		if !endpoint.ejectedUntil.IsZero() {
+			// Back after its ejection. Unless it was drained, one more failure ejects it again.
+			endpoint.ejectedUntil = time.Time{}
+			e.healthy.WithLabelValues(endpoint.Address).Set(1)
+		}
+		candidates = append(candidates, endpoint)
+	}
+
+	if len(candidates) == 0 {
+		e.logger.Warn("All endpoints are ejected, trying every endpoint", zap.Int("endpoints", len(e.endpoints)))
+		candidates = append(candidates, e.endpoints...)
+	}
+
+	switch e.config.Strategy {
+	case StrategyRoundRobin:
+		start := e.rotation % len(candidates)
+		e.rotation++
+		candidates = slices.Concat(candidates[start:], candidates[:start])
+	case StrategyLowestRTT:
+		// Endpoints without a measurement sort first, so each is measured once
+		slices.SortStableFunc(candidates, func(a, b *Endpoint) int {
+			return cmp.Compare(a.rtt, b.rtt)
+		})
+	default:
+		slices.SortStableFunc(candidates, func(a, b *Endpoint) int {
+			return a.Priority - b.Priority
+		})
+	}
+
+	first := ""
+	if !e.config.DisableSticky {
+		first = e.current
+	}
+	if e.redirect != "" {
+		first = e.redirect
+		e.redirect = ""
+	}
+
+	if first != "" {
+		if i := slices.IndexFunc(candidates, func(endpoint *Endpoint) bool { return endpoint.Address == first }); i > 0 {
+			endpoint := candidates[i]
+			candidates = slices.Insert(slices.Delete(candidates, i, i+1), 0, endpoint)
+		}
+	}
+
+	return candidates, nil
+}
+
+// Drained takes an endpoint out of rotation for the ejection duration after its server asked the
+// client to leave, as it is about to shut down. The next connection goes to redirect when set,
+// which joins the endpoints if it is not one of them.
+func (e *Endpoints) Drained(endpoint *Endpoint, redirect string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	endpoint.ejectedUntil = time.Now().Add(e.config.EjectionDuration)
+	e.healthy.WithLabelValues(endpoint.Address).Set(0)
+
+	if redirect != endpoint.Address {
+		e.redirectTo(redirect)
+	}
+}
+
+// Redirect makes the next connection go to address when set, which joins the endpoints if it is not
+// one of them
+func (e *Endpoints) Redirect(address string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.redirectTo(address)
+}
+
+// redirectTo sets the endpoint of the next connection. Callers must hold e.mu.
+func (e *Endpoints) redirectTo(address string) {
+	if address == "" {
+		return
+	}
+
+	if !slices.ContainsFunc(e.endpoints, func(known *Endpoint) bool { return known.Address == address }) {
+		e.endpoints = append(e.endpoints, &Endpoint{Address: address, Priority: len(e.endpoints)})
+		e.healthy.WithLabelValues(address).Set(1)
+	}
+	e.redirect = address
+}
+
+// Connected records a successful connection to an endpoint, restoring its health
+func (e *Endpoints) Connected(endpoint *Endpoint) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	endpoint.failures = 0
+	endpoint.ejectedUntil = time.Time{}
+	e.healthy.WithLabelValues(endpoint.Address).Set(1)
+
+	if e.current != "" && e.current != endpoint.Address {
+		e.failovers.Inc()
+		e.logger.Info("Failed over to another endpoint",
+			zap.String("from", e.current),
+			zap.String("to", endpoint.Address))
+	}
+	e.current = endpoint.Address
+}
+
+// Failed records a failed connection attempt, ejecting the endpoint once it reaches the failure threshold
+func (e *Endpoints) Failed(endpoint *Endpoint, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	endpoint.failures++
+	if endpoint.failures < e.config.FailureThreshold {
+		return
+	}
+
+	endpoint.ejectedUntil = time.Now().Add(e.config.EjectionDuration)
+	e.healthy.WithLabelValues(endpoint.Address).Set(0)
+
+	e.logger.Warn("Endpoint ejected",
+		zap.String("endpoint", endpoint.Address),
+		zap.Int("failures", endpoint.failures),
+		zap.Duration("ejection_duration", e.config.EjectionDuration),
+		zap.Error(err))
+}
+
+// ObserveRTT folds a handshake time into the smoothed RTT of an endpoint
+func (e *Endpoints) ObserveRTT(endpoint *Endpoint, rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if endpoint.rtt == 0 {
+		endpoint.rtt = rtt
+		return
+	}
+
+	endpoint.rtt = time.Duration((1-rttWeight)*float64(endpoint.rtt) + rttWeight*float64(rtt))
+}
+
+// refresh resolves the SRV name when the last resolution is older than the refresh interval.
+// A failed lookup keeps the known endpoints and is only an error when there are none. A pending
+// redirect is kept even when its address is not in the records.
+func (e *Endpoints) refresh(ctx context.Context) error {
+	e.mu.Lock()
+	due := time.Since(e.resolved) >= e.config.RefreshInterval
+	e.mu.Unlock()
+
+	if !due {
+		return nil
+	}
+
+	// The records come sorted by priority and shuffled by weight within a priority
+	_, records, err := e.resolver.LookupSRV(ctx, "", "", e.config.SRV)
+	if err == nil && len(records) == 0 {
+		err = errors.New("no records")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		if len(e.endpoints) == 0 {
+			return errors.New("Failed to resolve " + e.config.SRV + ": " + err.Error())
+		}
+
+		e.logger.Warn("Failed to resolve endpoints, keeping the known ones",
+			zap.String("srv", e.config.SRV),
+			zap.Error(err))
+		e.resolved = time.Now()
+
+		return nil
+	}
+
+	// Endpoints still present keep their health and RTT
+	known := make(map[string]*Endpoint, len(e.endpoints))
+	for _, endpoint := range e.endpoints {
+		known[endpoint.Address] = endpoint
+	}
+
+	endpoints := make([]*Endpoint, 0, len(records))
+	for i, record := range records {
+		address := net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port)))
+		endpoint, ok := known[address]
+		if !ok {
+			endpoint = &Endpoint{Address: address}
+			e.healthy.WithLabelValues(address).Set(1)
+		}
+		delete(known, address)
+
+		endpoint.Priority = i
+		endpoints = append(endpoints, endpoint)
+	}
+
+	// A redirect outside the records stays until the next connection went to it
+	if redirect, ok := known[e.redirect]; ok {
+		delete(known, e.redirect)
+
+		redirect.Priority = len(endpoints)
+		endpoints = append(endpoints, redirect)
+	}
+
+	for address := range known {
+		e.healthy.DeleteLabelValues(address)
+	}
+
+	if len(known) > 0 || len(endpoints) != len(e.endpoints) {
+		addresses := make([]string, len(endpoints))
+		for i, endpoint := range endpoints {
+			addresses[i] = endpoint.Address
+		}
+		e.logger.Info("Endpoints resolved", zap.String("srv", e.config.SRV), zap.Strings("endpoints", addresses))
+	}
+
+	e.endpoints = endpoints
+	e.resolved = time.Now()
+
+	return nil
+}
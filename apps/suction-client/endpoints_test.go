@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go/common"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const testSRV = "_suction._udp.test."
+
+// dnsStub is a name server answering SRV queries with the records it holds, or with NXDOMAIN
+// while failing
+type dnsStub struct {
+	conn net.PacketConn
+
+	mu      sync.Mutex
+	records []dnsmessage.SRVResource
+	failing bool
+	queries int
+}
+
+func newDNSStub(t *testing.T, records ...dnsmessage.SRVResource) *dnsStub {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &dnsStub{conn: conn, records: records}
+	go stub.serve()
+	t.Cleanup(func() { conn.Close() })
+
+	return stub
+}
+
+func (s *dnsStub) serve() {
+	buffer := make([]byte, 1500)
+	for {
+		n, addr, err := s.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+
+		if response, err := s.answer(buffer[:n]); err == nil {
+			s.conn.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *dnsStub) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queries++
+
+	responseHeader := dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true}
+	if s.failing {
+		responseHeader.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, responseHeader)
+	builder.StartQuestions()
+	builder.Question(question)
+	builder.StartAnswers()
+	if !s.failing && question.Type == dnsmessage.TypeSRV {
+		for _, record := range s.records {
+			resource := dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeSRV, Class: dnsmessage.ClassINET, TTL: 30}
+			if err := builder.SRVResource(resource, record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return builder.Finish()
+}
+
+func (s *dnsStub) set(records ...dnsmessage.SRVResource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = records
+	s.failing = false
+}
+
+func (s *dnsStub) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failing = true
+}
+
+func (s *dnsStub) queryCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queries
+}
+
+func srv(target string, port, priority, weight uint16) dnsmessage.SRVResource {
+	return dnsmessage.SRVResource{
+		Priority: priority,
+		Weight:   weight,
+		Port:     port,
+		Target:   dnsmessage.MustNewName(target),
+	}
+}
+
+// newTestEndpoints resolves testSRV through the stub with the endpoint settings changed by configure
+func newTestEndpoints(t *testing.T, stub *dnsStub, configure func(*common.ClientEndpoints)) *Endpoints {
+	config := &common.Config{}
+	config.Client.Endpoints = common.ClientEndpoints{
+		SRV:              testSRV,
+		Resolver:         stub.conn.LocalAddr().String(),
+		RefreshInterval:  time.Hour,
+		Strategy:         StrategyPriority,
+		FailureThreshold: 3,
+		EjectionDuration: time.Hour,
+	}
+	if configure != nil {
+		configure(&config.Client.Endpoints)
+	}
+
+	endpoints, err := NewEndpoints(zap.NewNop(), config, common.NewMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return endpoints
+}
+
+func candidates(t *testing.T, endpoints *Endpoints) []string {
+	found, err := endpoints.Candidates(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addresses := make([]string, len(found))
+	for i, endpoint := range found {
+		addresses[i] = endpoint.Address
+	}
+
+	return addresses
+}
+
+func endpoint(endpoints *Endpoints, address string) *Endpoint {
+	endpoints.mu.Lock()
+	defer endpoints.mu.Unlock()
+
+	i := slices.IndexFunc(endpoints.endpoints, func(endpoint *Endpoint) bool { return endpoint.Address == address })
+	if i < 0 {
+		return nil
+	}
+
+	return endpoints.endpoints[i]
+}
+
+func TestEndpointsSRVOrder(t *testing.T) {
+	stub := newDNSStub(t,
+		srv("backup.test.", 3, 20, 1),
+		srv("light.test.", 2, 10, 0),
+		srv("heavy.test.", 1, 10, 100),
+	)
+	endpoints := newTestEndpoints(t, stub, func(config *common.ClientEndpoints) {
+		config.RefreshInterval = time.Nanosecond
+	})
+
+	// Records come in priority order. Within a priority the weight shuffles them, and a record of
+	// weight 0 is only picked after every record with a weight.
+	want := []string{"heavy.test:1", "light.test:2", "backup.test:3"}
+	for range 20 {
+		if got := candidates(t, endpoints); !slices.Equal(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if stub.queryCount() < 20 {
+		t.Errorf("resolved %d times, want one lookup per call past the refresh interval", stub.queryCount())
+	}
+}
+
+func TestEndpointsEjection(t *testing.T) {
+	stub := newDNSStub(t, srv("a.test.", 1, 10, 1), srv("b.test.", 2, 20, 1))
+	endpoints := newTestEndpoints(t, stub, func(config *common.ClientEndpoints) {
+		config.FailureThreshold = 2
+		config.EjectionDuration = 100 * time.Millisecond
+	})
+
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	failure := errors.New("handshake timeout")
+	endpoints.Failed(endpoint(endpoints, "a.test:1"), failure)
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("ejected before the failure threshold: got %v, want %v", got, want)
+	}
+
+	endpoints.Failed(endpoint(endpoints, "a.test:1"), failure)
+	if got, want := candidates(t, endpoints), []string{"b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("not ejected at the failure threshold: got %v, want %v", got, want)
+	}
+
+	// With every endpoint ejected, all of them are tried rather than none
+	endpoints.Failed(endpoint(endpoints, "b.test:2"), failure)
+	endpoints.Failed(endpoint(endpoints, "b.test:2"), failure)
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("all ejected: got %v, want %v", got, want)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("after the ejection: got %v, want %v", got, want)
+	}
+
+	// Back from its ejection, an endpoint is ejected again by its next failure until a connection
+	// to it succeeds
+	endpoints.Failed(endpoint(endpoints, "a.test:1"), failure)
+	if got, want := candidates(t, endpoints), []string{"b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("not ejected again after the ejection: got %v, want %v", got, want)
+	}
+
+	endpoints.Connected(endpoint(endpoints, "a.test:1"))
+	endpoints.Failed(endpoint(endpoints, "a.test:1"), failure)
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("a connection did not reset the failures: got %v, want %v", got, want)
+	}
+}
+
+func TestEndpointsRefresh(t *testing.T) {
+	stub := newDNSStub(t, srv("a.test.", 1, 10, 1), srv("b.test.", 2, 20, 1))
+	endpoints := newTestEndpoints(t, stub, func(config *common.ClientEndpoints) {
+		config.FailureThreshold = 1
+		config.RefreshInterval = 100 * time.Millisecond
+	})
+
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	endpoints.Failed(endpoint(endpoints, "b.test:2"), errors.New("connection refused"))
+
+	// New records only apply once the refresh interval passed
+	stub.set(srv("c.test.", 3, 5, 1), srv("b.test.", 2, 10, 1))
+	if got, want := candidates(t, endpoints), []string{"a.test:1"}; !slices.Equal(got, want) {
+		t.Fatalf("before the refresh interval: got %v, want %v", got, want)
+	}
+	if stub.queryCount() != 1 {
+		t.Fatalf("resolved %d times before the refresh interval, want 1", stub.queryCount())
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	// An endpoint that stays in the records keeps its health, so b is still ejected
+	if got, want := candidates(t, endpoints), []string{"c.test:3"}; !slices.Equal(got, want) {
+		t.Fatalf("after the refresh: got %v, want %v", got, want)
+	}
+	if endpoint(endpoints, "a.test:1") != nil {
+		t.Errorf("endpoint a.test:1 left the records but is still known")
+	}
+
+	// A failed lookup keeps the known endpoints
+	stub.fail()
+	time.Sleep(150 * time.Millisecond)
+
+	if got, want := candidates(t, endpoints), []string{"c.test:3"}; !slices.Equal(got, want) {
+		t.Fatalf("after a failed lookup: got %v, want %v", got, want)
+	}
+}
+
+func TestEndpointsRedirectAcrossRefresh(t *testing.T) {
+	stub := newDNSStub(t, srv("a.test.", 1, 10, 1), srv("b.test.", 2, 20, 1))
+	endpoints := newTestEndpoints(t, stub, func(config *common.ClientEndpoints) {
+		config.RefreshInterval = 100 * time.Millisecond
+	})
+
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	// A redirect outside the records survives the refresh before the next connection
+	endpoints.Redirect("r.test:9")
+	time.Sleep(150 * time.Millisecond)
+
+	if got, want := candidates(t, endpoints), []string{"r.test:9", "a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("redirect after a refresh: got %v, want %v", got, want)
+	}
+
+	// Once followed, it leaves with the next refresh as it is not in the records
+	time.Sleep(150 * time.Millisecond)
+
+	if got, want := candidates(t, endpoints), []string{"a.test:1", "b.test:2"}; !slices.Equal(got, want) {
+		t.Fatalf("after the redirect: got %v, want %v", got, want)
+	}
+}
+
+func TestEndpointsResolveFailure(t *testing.T) {
+	stub := newDNSStub(t)
+	stub.fail()
+	endpoints := newTestEndpoints(t, stub, nil)
+
+	if _, err := endpoints.Candidates(t.Context()); err == nil {
+		t.Fatalf("resolved endpoints from a failing name server")
+	}
+}
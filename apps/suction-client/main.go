@@ -11,14 +11,17 @@ import (
 func main() {
 	app := fx.New(
 		common.Module,
-		fx.Provide(NewQuicClient),
+		fx.Provide(NewQuicClient, NewEndpoints),
+		common.ProvideReloadable[*QuicClient](),
 		fx.Invoke(func(logger *zap.Logger, quicClient *QuicClient) {
 			logger.Info("Starting application")
 			
 			// Start test data generation in background
 			go quicClient.generateTestData()
 		}),
-		fx.Invoke(func(lc fx.Lifecycle, logger *zap.Logger) {
+		fx.Invoke(func(lc fx.Lifecycle, logger *zap.Logger, config *common.Config, metrics *common.Metrics) {
+			metrics.Serve(lc, logger, config.Client.MetricsListeningAddress)
+
 			lc.Append(fx.Hook{
 				OnStop: func(ctx context.Context) error {
 					logger.Info("Stopping application")
@@ -4,43 +4,95 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go/common"
 	"go/pb"
 
 	"github.com/cenkalti/backoff/v5"
-	"github.com/golang/snappy"
 	"github.com/quic-go/quic-go"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
+)
+
+var (
+	errPoolFull   = errors.New("data pool full")
+	errPoolClosed = errors.New("data pool closed")
 )
 
 // DataPool represents a thread-safe pool for collecting sensor data
 type DataPool struct {
-	mu    sync.RWMutex
-	items []*pb.ClientData
+	mu       sync.RWMutex
+	items    []*pb.ClientData
+	maxItems int
+	dropped  uint64
+	closed   bool
 }
 
-// NewDataPool creates a new data pool
-func NewDataPool() *DataPool {
+// NewDataPool creates a new data pool holding at most maxItems items
+func NewDataPool(maxItems int) *DataPool {
 	return &DataPool{
-		items: make([]*pb.ClientData, 0),
+		items:    make([]*pb.ClientData, 0),
+		maxItems: maxItems,
 	}
 }
 
-// AddData adds data to the pool in a thread-safe manner
-func (dp *DataPool) AddData(data *pb.ClientData) {
+// AddData adds data to the pool in a thread-safe manner.
+// When the pool is full the oldest item is dropped and errPoolFull is returned.
+// A closed pool rejects the data with errPoolClosed.
+func (dp *DataPool) AddData(data *pb.ClientData) error {
 	dp.mu.Lock()
 	defer dp.mu.Unlock()
+
+	if dp.closed {
+		return errPoolClosed
+	}
+
+	var err error
+	if len(dp.items) >= dp.maxItems {
+		dp.items = dp.items[1:]
+		dp.dropped++
+		err = errPoolFull
+	}
+
 	dp.items = append(dp.items, data)
+
+	return err
+}
+
+// Requeue puts items that could not be delivered back ahead of the newer items, also when the pool
+// is closed, so they go out first. Beyond the capacity the oldest items are dropped.
+func (dp *DataPool) Requeue(items []*pb.ClientData) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	dp.items = append(items, dp.items...)
+	if overflow := len(dp.items) - dp.maxItems; overflow > 0 {
+		dp.items = dp.items[overflow:]
+		dp.dropped += uint64(overflow)
+	}
+}
+
+// Close stops the pool from accepting data. Items already in it can still be taken.
+func (dp *DataPool) Close() {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	dp.closed = true
 }
 
 // GetAndClearData retrieves all data from the pool and clears it
 func (dp *DataPool) GetAndClearData() []*pb.ClientData {
+	return dp.TakeData(0)
+}
+
+// TakeData removes and returns up to maxItems of the oldest items, or all of them when maxItems is 0
+func (dp *DataPool) TakeData(maxItems int) []*pb.ClientData {
 	dp.mu.Lock()
 	defer dp.mu.Unlock()
 
@@ -48,12 +100,17 @@ func (dp *DataPool) GetAndClearData() []*pb.ClientData {
 		return nil
 	}
 
+	count := len(dp.items)
+	if maxItems > 0 && maxItems < count {
+		count = maxItems
+	}
+
 	// Create a copy of the data
-	data := make([]*pb.ClientData, len(dp.items))
+	data := make([]*pb.ClientData, count)
 	copy(data, dp.items)
 
-	// Clear the pool
-	dp.items = dp.items[:0]
+	// Remove the taken items from the pool
+	dp.items = append(dp.items[:0], dp.items[count:]...)
 
 	return data
 }
@@ -65,29 +122,229 @@ func (dp *DataPool) GetDataCount() int {
 	return len(dp.items)
 }
 
+// SetMaxItems changes the pool capacity, dropping the oldest items when it shrinks below the current size
+func (dp *DataPool) SetMaxItems(maxItems int) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	dp.maxItems = maxItems
+	if overflow := len(dp.items) - maxItems; overflow > 0 {
+		dp.items = dp.items[overflow:]
+		dp.dropped += uint64(overflow)
+	}
+}
+
+// GetMaxItems returns the pool capacity
+func (dp *DataPool) GetMaxItems() int {
+	dp.mu.RLock()
+	defer dp.mu.RUnlock()
+	return dp.maxItems
+}
+
+// GetDroppedCount returns the number of items dropped because the pool was full
+func (dp *DataPool) GetDroppedCount() uint64 {
+	dp.mu.RLock()
+	defer dp.mu.RUnlock()
+	return dp.dropped
+}
+
+// inflightBatches keeps the items of the batches written to the server until their ack arrives
+type inflightBatches struct {
+	mu      sync.Mutex
+	batches map[uint64][]*pb.ClientData
+}
+
+func (b *inflightBatches) add(sequence uint64, items []*pb.ClientData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.batches[sequence] = items
+}
+
+// ack settles a batch and returns its items
+func (b *inflightBatches) ack(sequence uint64) []*pb.ClientData {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items := b.batches[sequence]
+	delete(b.batches, sequence)
+
+	return items
+}
+
+// dropOldest removes the batch with the lowest sequence still waiting for its ack
+func (b *inflightBatches) dropOldest() (uint64, []*pb.ClientData, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.batches) == 0 {
+		return 0, nil, false
+	}
+
+	sequence := slices.Min(slices.Collect(maps.Keys(b.batches)))
+	items := b.batches[sequence]
+	delete(b.batches, sequence)
+
+	return sequence, items, true
+}
+
+// takeAll removes every batch still waiting for its ack and returns their items in sequence order
+func (b *inflightBatches) takeAll() ([]*pb.ClientData, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sequences := slices.Sorted(maps.Keys(b.batches))
+
+	var items []*pb.ClientData
+	for _, sequence := range sequences {
+		items = append(items, b.batches[sequence]...)
+	}
+	clear(b.batches)
+
+	return items, len(sequences)
+}
+
+// restoredItems tracks the items restored from the spool until each was delivered or dropped
+type restoredItems struct {
+	mu    sync.Mutex
+	items map[*pb.ClientData]struct{}
+}
+
+func (r *restoredItems) add(items []*pb.ClientData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = make(map[*pb.ClientData]struct{}, len(items))
+	for _, item := range items {
+		r.items[item] = struct{}{}
+	}
+}
+
+// settle removes the items and reports whether the last restored item was among them
+func (r *restoredItems) settle(items []*pb.ClientData) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		delete(r.items, item)
+	}
+
+	return len(r.items) == 0
+}
+
+var (
+	// errServerDraining ends a connection the server asked the client to leave
+	errServerDraining = errors.New("server draining")
+	// errReconnecting ends a connection the server asked the client to replace
+	errReconnecting = errors.New("reconnecting")
+	// errClientStopping ends the connection once the pool was flushed on shutdown
+	errClientStopping = errors.New("client stopping")
+)
+
 type QuicClient struct {
-	logger   *zap.Logger
-	config   *common.Config
-	tls      *common.Tls
-	conn     *quic.Conn
-	dataPool *DataPool
+	logger        *zap.Logger
+	config        *common.Config
+	tls           *common.ClientTLS
+	endpoints     *Endpoints
+	spool         *Spool
+	conn          *quic.Conn
+	dataPool      *DataPool
+	inflight      inflightBatches
+	restored      restoredItems
+	holdUntil     atomic.Int64
+	flushInterval atomic.Int64
+	sequence      atomic.Uint64
+	bytesPerItem  atomic.Uint64
+	pacing        *pacingMetrics
+	connected     atomic.Bool
+	stopping      chan struct{}
+	done          chan struct{}
+
+	// startedAt, lastError and rejectedBatches are reported in the status of the client
+	startedAt       time.Time
+	lastError       atomic.Pointer[clientError]
+	rejectedBatches atomic.Uint64
+
+	// local holds the reloadable settings of the configuration, pushed the configuration last pushed
+	// by the server, whose values take precedence
+	settingsMu  sync.Mutex
+	local       clientSettings
+	pushed      *pb.RemoteConfig
+	configCache *ConfigCache
+}
+
+// dataStream is the authenticated stream batches are written to. Its reader hands the drains,
+// control commands and backpressure of the server to the connection loop and closes acksDone once
+// the server closed the stream.
+type dataStream struct {
+	stream       *quic.Stream
+	reader       *common.FrameReader
+	drains       chan *pb.Drain
+	controls     chan *pb.Control
+	backpressure chan *pb.Backpressure
+	acksDone     chan struct{}
+}
+
+// slowDown raises the flush interval until it expires, as asked by the server
+type slowDown struct {
+	interval time.Duration
+	until    time.Time
+}
+
+// apply returns the flush interval to use instead of interval
+func (s slowDown) apply(interval time.Duration) time.Duration {
+	if time.Now().Before(s.until) {
+		return max(interval, s.interval)
+	}
+
+	return interval
 }
 
 // AddExternalData adds external data to the client's data pool
 func (qc *QuicClient) AddExternalData(data *pb.ClientData) {
-	qc.dataPool.AddData(data)
+	switch err := qc.dataPool.AddData(data); {
+	case errors.Is(err, errPoolClosed):
+		qc.logger.Debug("Client is stopping, rejected item", zap.String("message", data.Message))
+		return
+	case errors.Is(err, errPoolFull):
+		qc.logger.Warn("Data pool is full, dropped oldest item",
+			zap.Int("pool_max_items", qc.dataPool.GetMaxItems()),
+			zap.Uint64("dropped_total", qc.dataPool.GetDroppedCount()))
+	}
+
 	qc.logger.Debug("External data added to pool",
 		zap.Int("pool_size", qc.dataPool.GetDataCount()),
 		zap.String("message", data.Message))
 }
 
-func NewQuicClient(logger *zap.Logger, config *common.Config, tls *common.Tls, lifecycle fx.Lifecycle) (*QuicClient, error) {
+func NewQuicClient(logger *zap.Logger, config *common.Config, tls *common.ClientTLS, endpoints *Endpoints, metrics *common.Metrics, lifecycle fx.Lifecycle) (*QuicClient, error) {
+	pacing, err := newPacingMetrics(metrics)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &QuicClient{
-		logger:   logger,
-		config:   config,
-		tls:      tls,
-		dataPool: NewDataPool(),
+		logger:    logger,
+		config:    config,
+		tls:       tls,
+		endpoints: endpoints,
+		spool:     NewSpool(config.Client.Shutdown.SpoolFile),
+		dataPool:  NewDataPool(config.Client.PoolMaxItems),
+		inflight:  inflightBatches{batches: make(map[uint64][]*pb.ClientData)},
+		pacing:    pacing,
+		stopping:  make(chan struct{}),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+		local: clientSettings{
+			poolMaxItems:  config.Client.PoolMaxItems,
+			flushInterval: config.Client.FlushInterval,
+		},
+		configCache: NewConfigCache(config.Client.ConfigPush.CacheFile),
 	}
+	client.flushInterval.Store(int64(config.Client.FlushInterval))
 
 	var cancel context.CancelFunc
 
@@ -95,6 +352,14 @@ func NewQuicClient(logger *zap.Logger, config *common.Config, tls *common.Tls, l
 		OnStart: func(ctx context.Context) error {
 			logger.Info("Starting QUIC client, attempting to connect...")
 
+			if err := client.restorePushedConfig(); err != nil {
+				return err
+			}
+
+			if err := client.restoreSpool(); err != nil {
+				return err
+			}
+
 			quicCtx, c := context.WithCancel(context.Background())
 			cancel = c
 
@@ -105,14 +370,33 @@ func NewQuicClient(logger *zap.Logger, config *common.Config, tls *common.Tls, l
 		OnStop: func(ctx context.Context) error {
 			logger.Info("Stopping QUIC client")
 
+			// Stop intake, then let the connection send what is left and wait for the acks
+			client.dataPool.Close()
+			close(client.stopping)
+
 			if cancel != nil {
+				if client.connected.Load() {
+					timer := time.NewTimer(config.Client.Shutdown.Timeout)
+					select {
+					case <-client.done:
+					case <-timer.C:
+						logger.Warn("Shutdown deadline reached before every batch was acknowledged",
+							zap.Duration("timeout", config.Client.Shutdown.Timeout))
+					case <-ctx.Done():
+					}
+					timer.Stop()
+				}
+
 				cancel()
+				<-client.done
 			}
 
 			if client.conn != nil {
-				return client.conn.CloseWithError(0, "client closing")
+				client.conn.CloseWithError(common.ErrorCodeNoError, "client closing")
 			}
 
+			client.spoolUnsent()
+
 			return nil
 		},
 	})
@@ -120,34 +404,153 @@ func NewQuicClient(logger *zap.Logger, config *common.Config, tls *common.Tls, l
 	return client, nil
 }
 
+// Reload applies reloadable client settings to the running client. Settings pushed by the server
+// keep precedence.
+func (qc *QuicClient) Reload(event common.ReloadEvent) {
+	qc.settingsMu.Lock()
+	qc.local = clientSettings{
+		poolMaxItems:  event.Current.Client.PoolMaxItems,
+		flushInterval: event.Current.Client.FlushInterval,
+	}
+	qc.settingsMu.Unlock()
+
+	settings := qc.applySettings()
+
+	qc.logger.Info("QUIC client settings reloaded",
+		zap.Int("pool_max_items", settings.poolMaxItems),
+		zap.Duration("flush_interval", settings.flushInterval))
+}
+
+// restoreSpool puts the items spooled by the previous run into the pool, ahead of new data. The
+// spool keeps them until settle saw the last of them delivered.
+func (qc *QuicClient) restoreSpool() error {
+	if !qc.spool.Enabled() {
+		return nil
+	}
+
+	items, err := qc.spool.Load()
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	qc.restored.add(items)
+	qc.dataPool.Requeue(items)
+
+	qc.logger.Info("Restored spooled items", zap.Int("items", len(items)))
+
+	return nil
+}
+
+// settle marks the items of a batch the server acknowledged or rejected for good. Once every item
+// restored from the spool is settled, the spool lets go of them.
+func (qc *QuicClient) settle(items []*pb.ClientData) {
+	if !qc.restored.settle(items) {
+		return
+	}
+
+	if err := qc.spool.Settle(); err != nil {
+		qc.logger.Error("Failed to settle spooled items", zap.Error(err))
+		return
+	}
+
+	qc.logger.Info("Spooled items delivered")
+}
+
+// spoolUnsent takes what is left in the pool once the client stopped and writes it to the spool
+func (qc *QuicClient) spoolUnsent() {
+	items := qc.dataPool.GetAndClearData()
+	if len(items) == 0 {
+		qc.logger.Info("Data pool flushed")
+		return
+	}
+
+	if !qc.spool.Enabled() {
+		qc.logger.Error("Unsent items lost on shutdown, no spool file configured", zap.Int("items", len(items)))
+		return
+	}
+
+	if err := qc.spool.Write(items); err != nil {
+		qc.logger.Error("Unsent items lost on shutdown", zap.Int("items", len(items)), zap.Error(err))
+		return
+	}
+
+	// Restored items not delivered yet went back to the pool, so they were just spooled again
+	if err := qc.spool.Settle(); err != nil {
+		qc.logger.Error("Failed to settle spooled items", zap.Error(err))
+	}
+
+	qc.logger.Info("Spooled unsent items", zap.Int("items", len(items)))
+}
+
 func (qc *QuicClient) start(ctx context.Context) {
+	defer close(qc.done)
+
 	operation := func() (string, error) {
+		select {
+		case <-qc.stopping:
+			return "", backoff.Permanent(errClientStopping)
+		default:
+		}
+
 		qc.logger.Info("Attempting to connect to QUIC server...")
 
-		conn, err := qc.connect(ctx)
+		conn, endpoint, err := qc.connect(ctx)
 		if err != nil {
 			return "", err
 		}
 		qc.conn = conn
-		qc.logger.Info("Connected to QUIC server", zap.String("remote", qc.conn.RemoteAddr().String()))
+		qc.logger.Info("Connected to QUIC server",
+			zap.String("endpoint", endpoint.Address),
+			zap.String("remote", qc.conn.RemoteAddr().String()))
+
+		err = qc.handleConnection(ctx, endpoint)
+		if errors.Is(err, errServerDraining) || errors.Is(err, errReconnecting) {
+			// The server asked for it and every batch sent was acknowledged, so reconnect right away
+			return "", backoff.RetryAfter(0)
+		}
+		if errors.Is(err, errClientStopping) {
+			return "", backoff.Permanent(err)
+		}
 
-		return "", qc.handleConnection(ctx)
+		// A rejected credential is rejected again on every endpoint
+		if code, ok := common.ErrorCodeFromError(err); errors.Is(err, common.ErrAuthDenied) || ok && !common.ErrorCodeRetryable(code) {
+			return "", backoff.Permanent(err)
+		}
+
+		// The connection outlives a failed stream, so it is closed before the next one is made
+		qc.conn.CloseWithError(common.ErrorCodeInternal, "data stream failed")
+
+		return "", err
 	}
 
 	b := backoff.NewExponentialBackOff()
-	b.InitialInterval = 5 * time.Second
-	b.MaxInterval = 60 * time.Second
+	b.InitialInterval = qc.config.Client.BackoffInitialInterval
+	b.MaxInterval = qc.config.Client.BackoffMaxInterval
 
 	options := []backoff.RetryOption{
 		backoff.WithBackOff(b),
 		backoff.WithMaxElapsedTime(0),
 		backoff.WithNotify(func(err error, d time.Duration) {
-			qc.logger.Error("Connection failed. Retrying...", zap.Error(err), zap.String("retry_in", d.String()))
+			var retryAfter *backoff.RetryAfterError
+			if errors.As(err, &retryAfter) {
+				return
+			}
+
+			fields := []zap.Field{zap.Error(err), zap.String("retry_in", d.String())}
+			if code, ok := common.ErrorCodeFromError(err); ok {
+				fields = append(fields, zap.String("error_code", common.ErrorCodeName(code)))
+			}
+
+			qc.recordError(err)
+			qc.logger.Error("Connection failed. Retrying...", fields...)
 		}),
 	}
 
 	if _, err := backoff.Retry(ctx, operation, options...); err != nil {
-		if errors.Is(err, context.Canceled) {
+		if errors.Is(err, context.Canceled) || errors.Is(err, errClientStopping) {
 			qc.logger.Info("Client shutdown initiated.")
 		} else {
 			qc.logger.Fatal("Critical: Connection failed permanently.", zap.Error(err))
@@ -155,32 +558,162 @@ func (qc *QuicClient) start(ctx context.Context) {
 	}
 }
 
-func (qc *QuicClient) connect(ctx context.Context) (*quic.Conn, error) {
-	quicConfig := &quic.Config{
-		MaxIdleTimeout:                 time.Duration(qc.config.QuicMaxIdleTimeout) * time.Second,
-		KeepAlivePeriod:                time.Duration(qc.config.QuicKeepAlivePeriod) * time.Second,
-		MaxIncomingStreams:             qc.config.QuicServerMaxIncomingStreams,
-		MaxIncomingUniStreams:          qc.config.QuicServerMaxIncomingUniStreams,
-		InitialStreamReceiveWindow:     qc.config.QuicServerInitialStreamReceiveWindow,
-		MaxStreamReceiveWindow:         qc.config.QuicServerMaxStreamReceiveWindow,
-		InitialConnectionReceiveWindow: qc.config.QuicServerInitialConnectionReceiveWindow,
-		MaxConnectionReceiveWindow:     qc.config.QuicServerMaxConnectionReceiveWindow,
+// connect dials the endpoints in the order of the selection strategy and returns the first
+// connection made. A failed dial counts against the health of its endpoint right away, the
+// outcome of the handshake once it is known.
+func (qc *QuicClient) connect(ctx context.Context) (*quic.Conn, *Endpoint, error) {
+	candidates, err := qc.endpoints.Candidates(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errs []error
+	for _, endpoint := range candidates {
+		started := time.Now()
+
+		conn, err := qc.dial(ctx, endpoint.Address)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+
+			qc.logger.Warn("Failed to connect to endpoint", zap.String("endpoint", endpoint.Address), zap.Error(err))
+			qc.endpoints.Failed(endpoint, err)
+			errs = append(errs, errors.New(endpoint.Address+": "+err.Error()))
+			continue
+		}
+
+		go qc.watchHandshake(conn, endpoint, started)
+
+		return conn, endpoint, nil
 	}
 
-	return quic.DialAddr(ctx, qc.config.QuicClientConnectionAddress, qc.tls.Config, quicConfig)
+	return nil, nil, errors.Join(errs...)
 }
 
-func (qc *QuicClient) handleConnection(ctx context.Context) error {
-	qc.logger.Info("handleConnection started...")
+// watchHandshake reports the handshake of a connection to the endpoint health. An early connection
+// is returned before its handshake, so an unreachable endpoint only shows up here.
+func (qc *QuicClient) watchHandshake(conn *quic.Conn, endpoint *Endpoint, started time.Time) {
+	select {
+	case <-conn.HandshakeComplete():
+		qc.endpoints.Connected(endpoint)
+		qc.endpoints.ObserveRTT(endpoint, time.Since(started))
+	case <-conn.Context().Done():
+		qc.endpoints.Failed(endpoint, context.Cause(conn.Context()))
+	}
+}
+
+// dial connects to a server. With a cached session ticket and 0-RTT enabled the connection is
+// returned before the handshake completes, so the first frames go out with the handshake.
+func (qc *QuicClient) dial(ctx context.Context, address string) (*quic.Conn, error) {
+	quicConfig := common.NewClientQuicConfig(qc.config.Client)
+
+	if resumption := qc.config.Client.Resumption; !resumption.Disabled && !resumption.Disable0RTT {
+		return quic.DialAddrEarly(ctx, address, qc.tls.Config, quicConfig)
+	}
+
+	return quic.DialAddr(ctx, address, qc.tls.Config, quicConfig)
+}
+
+// openStream opens the data stream and authenticates on it, returning the reader the server
+// messages that follow are read with. When the server rejected the 0-RTT data, the frames are sent
+// again on the connection that continues after the handshake.
+func (qc *QuicClient) openStream(ctx context.Context) (*quic.Stream, *common.FrameReader, error) {
+	var reader *common.FrameReader
 
 	stream, err := qc.conn.OpenStreamSync(ctx)
+	if err == nil {
+		reader = common.NewFrameReader(stream, common.FrameHeaderSize, common.ControlFrameMaxSize)
+		err = qc.authenticate(stream, reader)
+		if err != nil {
+			stream.Close()
+		}
+	}
+
+	if errors.Is(err, quic.Err0RTTRejected) {
+		return qc.reopenStream(ctx)
+	}
 	if err != nil {
-		qc.logger.Error("Failed to open stream", zap.Error(err))
+		return nil, nil, err
+	}
+
+	state := qc.conn.ConnectionState()
+	qc.logger.Debug("Data stream ready",
+		zap.Bool("resumed", state.TLS.DidResume),
+		zap.Bool("used_0rtt", state.Used0RTT))
+
+	return stream, reader, nil
+}
+
+// reopenStream continues on the connection that follows a rejected 0-RTT handshake and opens the
+// data stream again, as everything sent in the 0-RTT data was dropped
+func (qc *QuicClient) reopenStream(ctx context.Context) (*quic.Stream, *common.FrameReader, error) {
+	qc.logger.Info("Server rejected 0-RTT data, resending after the handshake")
+
+	conn, err := qc.conn.NextConnection(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	qc.conn = conn
+
+	return qc.openStream(ctx)
+}
+
+func (qc *QuicClient) handleConnection(ctx context.Context, endpoint *Endpoint) error {
+	qc.logger.Info("handleConnection started...")
+
+	stream, reader, err := qc.openStream(ctx)
+	if err != nil {
+		switch code, _ := common.ErrorCodeFromError(err); code {
+		case common.ErrorCodeDraining:
+			qc.endpoints.Drained(endpoint, "")
+		case common.ErrorCodeServerBusy:
+			// The server is at capacity, so the next attempts prefer the other endpoints
+			qc.endpoints.Failed(endpoint, err)
+		}
+
+		qc.logger.Error("Failed to open authenticated stream", zap.Error(err))
 		return err
 	}
-	defer stream.Close()
+	ds := qc.newDataStream(stream, reader)
+	defer func() {
+		ds.stream.Close()
+	}()
+
+	// send sends a batch from the pool. Without a credential the stream carries nothing before the
+	// first batch, so a rejection of the 0-RTT data may only show here; the batch then goes out
+	// again on a new stream.
+	send := func(maxItems int) error {
+		err := qc.sendPool(ctx, ds, maxItems)
+		if !errors.Is(err, quic.Err0RTTRejected) {
+			return err
+		}
+
+		if ds, err = qc.replaceRejectedStream(ctx, ds); err != nil {
+			return err
+		}
+
+		return qc.sendPool(ctx, ds, maxItems)
+	}
+
+	streamsCtx, stopStreams := context.WithCancel(ctx)
+	defer stopStreams()
+
+	go qc.runSideStreams(streamsCtx, qc.conn)
+
+	qc.connected.Store(true)
+	defer qc.connected.Store(false)
+
+	// Batches without an ack when the connection ends go out again on the next one. The server may
+	// have stored some of them, so delivery is at least once.
+	defer qc.requeueUnacked()
 
-	ticker := time.NewTicker(1 * time.Second)
+	var (
+		slow slowDown
+		rate sendRate
+	)
+	flushInterval, _ := qc.pace(slow, rate)
+	ticker := time.NewTicker(flushInterval)
 	defer ticker.Stop()
 
 	for {
@@ -189,88 +722,280 @@ func (qc *QuicClient) handleConnection(ctx context.Context) error {
 			qc.logger.Info("handleConnection received shutdown signal, stopping...",
 				zap.String("reason", ctx.Err().Error()))
 			return ctx.Err()
-		case <-ticker.C:
-			// Get all data from the pool
-			poolData := qc.dataPool.GetAndClearData()
-
-			if len(poolData) == 0 {
-				qc.logger.Debug("No data in pool to send")
-				continue
+		case <-qc.stopping:
+			// Whatever is left in the pool goes out before leaving, which waits until the server
+			// acknowledged every batch. ctx ends at the shutdown deadline at the latest.
+			if err := send(0); err != nil {
+				return err
+			}
+			if err := qc.leave(ctx, ds, "client closing"); err != nil {
+				return err
 			}
 
-			// Create batch message with all collected data
-			batchMessage := &pb.ClientData{
-				Timestamp:      time.Now().Unix(),
-				Message:        fmt.Sprintf("Batch transmission - %d items", len(poolData)),
-				SensorReadings: make([]float32, 0),
+			return errClientStopping
+		case drain := <-ds.drains:
+			qc.logger.Info("Server is draining, reconnecting",
+				zap.String("endpoint", endpoint.Address),
+				zap.String("redirect", drain.Endpoint),
+				zap.String("reason", drain.Reason))
+
+			qc.endpoints.Drained(endpoint, drain.Endpoint)
+			if err := qc.leave(ctx, ds, "server draining"); err != nil {
+				return err
 			}
 
-			// Combine all sensor readings from collected data
-			totalSensors := 0
-			for _, data := range poolData {
-				totalSensors += len(data.SensorReadings)
-				batchMessage.SensorReadings = append(batchMessage.SensorReadings, data.SensorReadings...)
+			return errServerDraining
+		case control := <-ds.controls:
+			switch control.Command {
+			case pb.ControlCommand_CONTROL_COMMAND_FLUSH:
+				qc.logger.Info("Server asked to flush now", zap.String("reason", control.Reason))
+
+				if err := send(0); err != nil {
+					return err
+				}
+			case pb.ControlCommand_CONTROL_COMMAND_SLOW_DOWN:
+				slow = slowDown{
+					interval: time.Duration(control.FlushIntervalMs) * time.Millisecond,
+					until:    time.Now().Add(time.Duration(control.DurationMs) * time.Millisecond),
+				}
+
+				qc.logger.Info("Server asked to slow down",
+					zap.Duration("flush_interval", slow.interval),
+					zap.Time("until", slow.until),
+					zap.String("reason", control.Reason))
+
+				flushInterval, _ = qc.pace(slow, rate)
+				ticker.Reset(flushInterval)
+			case pb.ControlCommand_CONTROL_COMMAND_RECONNECT:
+				qc.logger.Info("Server asked to reconnect",
+					zap.String("endpoint", endpoint.Address),
+					zap.String("redirect", control.Endpoint),
+					zap.String("reason", control.Reason))
+
+				qc.endpoints.Redirect(control.Endpoint)
+				if err := qc.leave(ctx, ds, "reconnecting"); err != nil {
+					return err
+				}
+
+				return errReconnecting
+			default:
+				qc.logger.Warn("Ignoring unknown control command", zap.Stringer("command", control.Command))
 			}
+		case signal := <-ds.backpressure:
+			qc.pacing.signals.Inc()
 
-			// TODO: Snappy Compression Testing Requirements
-			// 1. Data Size Testing:
-			//    - Test with 1KB, 5KB, 10KB, 20KB, 50KB, 100KB data sizes
-			//    - Compare compression ratios for different data volumes
-			//    - Find optimal data size threshold for compression
-			//
-			// 2. Data Pattern Testing:
-			//    - Test with repetitive data patterns (e.g., constant sensor values)
-			//    - Test with random data patterns (current implementation)
-			//    - Test with real sensor data patterns (temperature, humidity, pressure)
-			//    - Test with structured data patterns
-			//
-			// 3. Performance Testing:
-			//    - Measure compression time for different data sizes
-			//    - Measure decompression time on server side
-			//    - Monitor memory usage during compression/decompression
-			//    - Test CPU usage patterns
-			//
-			// 4. Network Performance Testing:
-			//    - Compare transmission time with/without compression
-			//    - Measure bandwidth usage reduction
-			//    - Test latency impact of compression
-			//
-			// 5. Error Handling Testing:
-			//    - Test with corrupted compressed data
-			//    - Test memory exhaustion scenarios
-			//    - Test network error handling
-			//
-			// 6. Optimization Opportunities:
-			//    - Implement conditional compression (only compress if beneficial)
-			//    - Compare with other compression algorithms (gzip, lz4)
-			//    - Test different compression levels
-			//    - Implement adaptive compression based on data characteristics
-
-			// Compress data with snappy
-			protobufData, err := proto.Marshal(batchMessage)
-			if err != nil {
-				qc.logger.Error("Failed to marshal protobuf message", zap.Error(err))
-				return err
+			previous := rate
+			rate = sendRate{load: signal.LoadPercent, bytesPerSecond: signal.SuggestedBytesPerSecond}
+
+			// Signals repeat while the server is under load, so the ticker only restarts on a change
+			interval, batchMax := qc.pace(slow, rate)
+			if interval != flushInterval {
+				flushInterval = interval
+				ticker.Reset(flushInterval)
 			}
 
-			compressedData := snappy.Encode(nil, protobufData)
-			compressionRatio := float64(len(compressedData)) / float64(len(protobufData)) * 100
+			fields := []zap.Field{
+				zap.Uint32("load_percent", rate.load),
+				zap.Uint64("suggested_bytes_per_second", rate.bytesPerSecond),
+				zap.Duration("flush_interval", flushInterval),
+				zap.Int("batch_max_items", batchMax),
+			}
+			switch {
+			case previous.bytesPerSecond == 0 && rate.bytesPerSecond != 0:
+				qc.logger.Warn("Server is under load, sending less", fields...)
+			case previous.bytesPerSecond != 0 && rate.bytesPerSecond == 0:
+				qc.logger.Info("Server load recovered, sending at full rate", fields...)
+			default:
+				qc.logger.Debug("Send rate adjusted", fields...)
+			}
+		case <-ticker.C:
+			interval, batchMax := qc.pace(slow, rate)
+			if interval != flushInterval {
+				flushInterval = interval
+				ticker.Reset(flushInterval)
+			}
 
-			_, err = stream.Write(compressedData)
-			if err != nil {
-				qc.logger.Error("Failed to write to stream", zap.Error(err))
+			if err := send(batchMax); err != nil {
 				return err
 			}
+		}
+	}
+}
+
+// newDataStream starts reading the server messages of an authenticated stream with the reader
+// the authentication was read with
+func (qc *QuicClient) newDataStream(stream *quic.Stream, reader *common.FrameReader) *dataStream {
+	ds := &dataStream{
+		stream:       stream,
+		reader:       reader,
+		drains:       make(chan *pb.Drain, 1),
+		controls:     make(chan *pb.Control, 8),
+		backpressure: make(chan *pb.Backpressure, 1),
+		acksDone:     make(chan struct{}),
+	}
+	go qc.readResponses(ds)
+
+	return ds
+}
+
+// replaceRejectedStream opens a new data stream after the server rejected the 0-RTT data the
+// stream was opened with. The reader of the rejected stream fails as well; once it exited, the
+// batches written to the stream go back to the pool to be sent on the new one.
+func (qc *QuicClient) replaceRejectedStream(ctx context.Context, ds *dataStream) (*dataStream, error) {
+	select {
+	case <-ds.acksDone:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	qc.requeueUnacked()
+
+	stream, reader, err := qc.reopenStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return qc.newDataStream(stream, reader), nil
+}
+
+// runSideStreams serves the control streams the server opens to push configuration and sends the
+// status reports until ctx ends. Both wait for the handshake, so they run on the connection that
+// continues after it whether the server accepted the 0-RTT data or not.
+func (qc *QuicClient) runSideStreams(ctx context.Context, conn *quic.Conn) {
+	conn, err := conn.NextConnection(ctx)
+	if err != nil || conn.Context().Err() != nil {
+		return
+	}
+
+	go qc.acceptConfigStreams(conn)
+
+	if !qc.config.Client.Heartbeat.Disabled {
+		qc.reportStatus(ctx, conn)
+	}
+}
+
+// sendPool sends the oldest items in the pool as one batch of at most maxItems, or everything in
+// the pool when maxItems is 0
+func (qc *QuicClient) sendPool(ctx context.Context, ds *dataStream, maxItems int) error {
+	// Items stay in the pool while the server asked to wait, and are spooled if the client stops
+	if until := time.Unix(0, qc.holdUntil.Load()); time.Now().Before(until) {
+		qc.logger.Debug("Holding batches until the server accepts them again", zap.Time("until", until))
+		return nil
+	}
+
+	poolData := qc.dataPool.TakeData(maxItems)
+	if len(poolData) == 0 {
+		qc.logger.Debug("No data in pool to send")
+		return nil
+	}
+
+	return qc.sendBatch(ctx, ds, poolData)
+}
 
-			qc.logger.Info("Batch snappy compressed protobuf transmission",
-				zap.Int("batch_items", len(poolData)),
-				zap.Int("total_sensors", totalSensors),
-				zap.Int("protobuf_size", len(protobufData)),
-				zap.Int("compressed_size", len(compressedData)),
-				zap.Float64("compression_ratio_percent", compressionRatio),
-				zap.Int("size_reduction_bytes", len(protobufData)-len(compressedData)))
+// holdSending keeps batches in the pool for wait, extending a hold already in place
+func (qc *QuicClient) holdSending(wait time.Duration) {
+	until := time.Now().Add(wait).UnixNano()
+	for {
+		current := qc.holdUntil.Load()
+		if current >= until || qc.holdUntil.CompareAndSwap(current, until) {
+			return
+		}
+	}
+}
+
+// leave closes the send side of the stream, which tells the server no batch follows, and closes the
+// connection once the server closed the stream after writing the acks of the batches already sent.
+// New data stays in the pool meanwhile.
+func (qc *QuicClient) leave(ctx context.Context, ds *dataStream, reason string) error {
+	ds.stream.Close()
+	select {
+	case <-ds.acksDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	qc.conn.CloseWithError(common.ErrorCodeNoError, reason)
+
+	return nil
+}
+
+// sendBatch combines the items into one batch and writes it to the data stream. The items stay in
+// flight until the server acknowledged the batch.
+func (qc *QuicClient) sendBatch(ctx context.Context, ds *dataStream, poolData []*pb.ClientData) error {
+	// Create batch message with all collected data. The message comes from a pool so its
+	// readings keep the capacity of earlier batches.
+	batchMessage := common.AcquireClientData()
+	batchMessage.Sequence = qc.sequence.Add(1)
+	batchMessage.Timestamp = time.Now().Unix()
+	batchMessage.Message = fmt.Sprintf("Batch transmission - %d items", len(poolData))
+
+	// Combine all sensor readings from collected data
+	totalSensors := 0
+	for _, data := range poolData {
+		totalSensors += len(data.SensorReadings)
+		batchMessage.SensorReadings = append(batchMessage.SensorReadings, data.SensorReadings...)
+	}
+
+	// Marshal and compress data with snappy
+	payload, protobufSize, err := common.EncodeClientDataPooled(batchMessage)
+	sequence := batchMessage.Sequence
+	common.ReleaseClientData(batchMessage)
+	if err != nil {
+		qc.dataPool.Requeue(poolData)
+		qc.logger.Error("Failed to marshal protobuf message", zap.Error(err))
+		return err
+	}
+
+	qc.inflight.add(sequence, poolData)
+
+	compressedSize := len(*payload)
+	qc.bytesPerItem.Store(uint64(compressedSize / len(poolData)))
+	compressionRatio := float64(compressedSize) / float64(protobufSize) * 100
+
+	err = common.WriteFrame(ds.stream, common.FrameClientData, *payload)
+	common.Buffers.Put(payload)
+	if err != nil {
+		if !errors.Is(err, quic.Err0RTTRejected) {
+			qc.logger.Error("Failed to write to stream", zap.Error(err))
 		}
+		return err
+	}
+
+	qc.logger.Info("Batch snappy compressed protobuf transmission",
+		zap.Uint64("sequence", sequence),
+		zap.Int("batch_items", len(poolData)),
+		zap.Int("total_sensors", totalSensors),
+		zap.Int("protobuf_size", protobufSize),
+		zap.Int("compressed_size", compressedSize),
+		zap.Float64("compression_ratio_percent", compressionRatio),
+		zap.Int("size_reduction_bytes", protobufSize-compressedSize))
+
+	return nil
+}
+
+// requeueUnacked puts the items of the batches still waiting for an ack back into the pool
+func (qc *QuicClient) requeueUnacked() {
+	items, batches := qc.inflight.takeAll()
+	if batches == 0 {
+		return
 	}
+
+	qc.dataPool.Requeue(items)
+
+	qc.logger.Warn("Requeued unacknowledged batches",
+		zap.Int("batches", batches),
+		zap.Int("items", len(items)))
+}
+
+// authenticate sends the configured credential as the first frame and waits for the server decision
+func (qc *QuicClient) authenticate(stream *quic.Stream, reader *common.FrameReader) error {
+	response, err := common.Authenticate(stream, reader, qc.config.Client.Auth)
+	if err != nil || response == nil {
+		return err
+	}
+
+	qc.logger.Info("Authenticated with QUIC server", zap.String("client_id", response.ClientId), zap.String("message", response.Message))
+
+	return nil
 }
 
 // generateTestData simulates external data being added to the pool
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// readResponses reads the frames the server writes back on the data stream and dispatches each to
+// its handler: an ack or an error for every data frame, a drain when the server shuts down, the
+// control commands of the server and its backpressure. acksDone is closed once the server closed the stream.
+func (qc *QuicClient) readResponses(ds *dataStream) {
+	defer close(ds.acksDone)
+
+	handlers := map[common.FrameType]func(payload []byte) error{
+		common.FrameAck:          qc.handleAck,
+		common.FrameError:        qc.handleError,
+		common.FrameDrain:        ds.handleDrain,
+		common.FrameControl:      ds.handleControl,
+		common.FrameBackpressure: ds.handleBackpressure,
+	}
+
+	for {
+		frame, err := ds.reader.ReadFrame()
+		if err != nil {
+			code, ok := common.ErrorCodeFromError(err)
+			switch {
+			case ok && code == common.ErrorCodeFrameTooLarge:
+				// Every batch before it was acknowledged, so the oldest one in flight is the one refused.
+				// Sending it again would only be refused again.
+				if sequence, items, ok := qc.inflight.dropOldest(); ok {
+					qc.settle(items)
+					qc.rejectedBatches.Add(1)
+					qc.recordError(err)
+					qc.logger.Error("Server refused a batch as too large, dropping it",
+						zap.Uint64("sequence", sequence),
+						zap.Int("items", len(items)))
+				}
+			case ok && code != common.ErrorCodeNoError && code != common.ErrorCodeDraining:
+				qc.logger.Warn("Server ended the data stream", zap.String("error_code", common.ErrorCodeName(code)), zap.Error(err))
+			case !errors.Is(err, io.EOF):
+				qc.logger.Debug("Stopped reading responses", zap.Error(err))
+			}
+			return
+		}
+
+		handle, ok := handlers[frame.Type]
+		if !ok {
+			qc.logger.Warn("Ignoring unexpected frame", zap.Stringer("frame_type", frame.Type))
+			frame.Release()
+			continue
+		}
+
+		err = handle(frame.Payload)
+		frame.Release()
+		if err != nil {
+			qc.logger.Warn("Ignoring invalid frame", zap.Stringer("frame_type", frame.Type), zap.Error(err))
+		}
+	}
+}
+
+// handleAck settles the batch of an ack. A batch refused by the rate limits goes back to the pool
+// and sending waits for the retry hint of the server; any other rejection is final and the batch
+// is dropped.
+func (qc *QuicClient) handleAck(payload []byte) error {
+	ack := &pb.Ack{}
+	if err := proto.Unmarshal(payload, ack); err != nil {
+		return err
+	}
+
+	items := qc.inflight.ack(ack.Sequence)
+
+	switch ack.Status {
+	case pb.AckStatus_ACK_STATUS_OK:
+		qc.settle(items)
+		qc.logger.Debug("Batch acknowledged", zap.Uint64("sequence", ack.Sequence))
+	case pb.AckStatus_ACK_STATUS_THROTTLED, pb.AckStatus_ACK_STATUS_QUOTA_EXCEEDED:
+		retryAfter := time.Duration(ack.RetryAfterMs) * time.Millisecond
+		qc.holdSending(retryAfter)
+		qc.dataPool.Requeue(items)
+		qc.pacing.deferred.Inc()
+
+		qc.logger.Warn("Server deferred batch, sending it again later",
+			zap.Uint64("sequence", ack.Sequence),
+			zap.String("status", ack.Status.String()),
+			zap.Int("items", len(items)),
+			zap.Duration("retry_after", retryAfter))
+	default:
+		qc.settle(items)
+		qc.rejectedBatches.Add(1)
+		qc.recordError(errors.New("Server rejected batch " + strconv.FormatUint(ack.Sequence, 10) + ": " + ack.Status.String() + " " + ack.Message))
+		qc.logger.Warn("Server rejected batch, dropping it",
+			zap.Uint64("sequence", ack.Sequence),
+			zap.String("status", ack.Status.String()),
+			zap.String("message", ack.Message),
+			zap.Int("items", len(items)))
+	}
+
+	return nil
+}
+
+// handleError logs an error reported by the server. An error about a batch settles it like a rejection.
+func (qc *QuicClient) handleError(payload []byte) error {
+	report := &pb.Error{}
+	if err := proto.Unmarshal(payload, report); err != nil {
+		return err
+	}
+
+	if report.Sequence != 0 {
+		qc.settle(qc.inflight.ack(report.Sequence))
+		qc.rejectedBatches.Add(1)
+	}
+	qc.recordError(errors.New("Server reported an error: " + common.ErrorCodeName(quic.ApplicationErrorCode(report.Code)) + " " + report.Message))
+
+	qc.logger.Warn("Server reported an error",
+		zap.String("error_code", common.ErrorCodeName(quic.ApplicationErrorCode(report.Code))),
+		zap.String("message", report.Message),
+		zap.Uint64("sequence", report.Sequence))
+
+	return nil
+}
+
+// handleDrain hands a drain to the connection loop. Only the first one counts.
+func (ds *dataStream) handleDrain(payload []byte) error {
+	drain := &pb.Drain{}
+	if err := proto.Unmarshal(payload, drain); err != nil {
+		return err
+	}
+
+	select {
+	case ds.drains <- drain:
+	default:
+	}
+
+	return nil
+}
+
+// handleControl hands a control command to the connection loop
+func (ds *dataStream) handleControl(payload []byte) error {
+	control := &pb.Control{}
+	if err := proto.Unmarshal(payload, control); err != nil {
+		return err
+	}
+
+	select {
+	case ds.controls <- control:
+		return nil
+	default:
+		return errors.New("too many pending control commands, dropped " + control.Command.String())
+	}
+}
+
+// handleBackpressure hands the latest backpressure to the connection loop, replacing one it did not
+// take yet
+func (ds *dataStream) handleBackpressure(payload []byte) error {
+	signal := &pb.Backpressure{}
+	if err := proto.Unmarshal(payload, signal); err != nil {
+		return err
+	}
+
+	select {
+	case <-ds.backpressure:
+	default:
+	}
+	ds.backpressure <- signal
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"go/common"
+	"go/pb"
+)
+
+// spoolFrameMaxSize bounds the frames read back from a spool file, each holding a single item
+const spoolFrameMaxSize = 16 * 1024 * 1024
+
+// Spool keeps the items that could not be delivered before shutdown in a file, so the next start
+// sends them. Each item is stored as a data frame. Loaded items stay in an in-flight file until
+// they were delivered, so a crash before that sends them again.
+type Spool struct {
+	path string
+}
+
+// NewSpool creates a spool at path. An empty path disables spooling.
+func NewSpool(path string) *Spool {
+	return &Spool{path: path}
+}
+
+// Enabled reports whether a spool file is configured
+func (s *Spool) Enabled() bool {
+	return s.path != ""
+}
+
+// Write appends the items to the spool file
+func (s *Spool) Write(items []*pb.ClientData) error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.New("Failed to open spool file " + s.path + ": " + err.Error())
+	}
+
+	for _, item := range items {
+		payload, _, err := common.EncodeClientData(item)
+		if err == nil {
+			err = common.WriteFrame(file, common.FrameClientData, payload)
+		}
+		if err != nil {
+			file.Close()
+			return errors.New("Failed to write spool file " + s.path + ": " + err.Error())
+		}
+	}
+
+	return file.Close()
+}
+
+// Size returns the size of the spool and in-flight files in bytes. A missing file holds no items.
+func (s *Spool) Size() (int64, error) {
+	var size int64
+	for _, path := range []string{s.path, s.inflightPath()} {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		size += info.Size()
+	}
+
+	return size, nil
+}
+
+// Load moves the spooled items to the in-flight file and returns them, after the items of an
+// in-flight file a previous run left behind. The in-flight file stays until Settle. A missing file
+// holds no items.
+func (s *Spool) Load() ([]*pb.ClientData, error) {
+	if err := s.moveToInflight(); err != nil {
+		return nil, err
+	}
+
+	return readSpoolFile(s.inflightPath())
+}
+
+// Settle removes the in-flight file once its items were delivered or spooled again
+func (s *Spool) Settle() error {
+	if err := os.Remove(s.inflightPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.New("Failed to remove spool file " + s.inflightPath() + ": " + err.Error())
+	}
+
+	return nil
+}
+
+func (s *Spool) inflightPath() string {
+	return s.path + ".inflight"
+}
+
+// moveToInflight appends the spool file to the in-flight file and removes it
+func (s *Spool) moveToInflight() error {
+	if _, err := os.Stat(s.inflightPath()); errors.Is(err, os.ErrNotExist) {
+		if err := os.Rename(s.path, s.inflightPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return errors.New("Failed to move spool file " + s.path + ": " + err.Error())
+		}
+		return nil
+	}
+
+	source, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.New("Failed to open spool file " + s.path + ": " + err.Error())
+	}
+	defer source.Close()
+
+	inflight, err := os.OpenFile(s.inflightPath(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return errors.New("Failed to open spool file " + s.inflightPath() + ": " + err.Error())
+	}
+	if _, err := io.Copy(inflight, source); err != nil {
+		inflight.Close()
+		return errors.New("Failed to write spool file " + s.inflightPath() + ": " + err.Error())
+	}
+	if err := inflight.Close(); err != nil {
+		return errors.New("Failed to write spool file " + s.inflightPath() + ": " + err.Error())
+	}
+
+	if err := os.Remove(s.path); err != nil {
+		return errors.New("Failed to remove spool file " + s.path + ": " + err.Error())
+	}
+
+	return nil
+}
+
+// readSpoolFile reads the items of a spool file. A missing file holds no items.
+func readSpoolFile(path string) ([]*pb.ClientData, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.New("Failed to open spool file " + path + ": " + err.Error())
+	}
+	defer file.Close()
+
+	reader := common.NewFrameReader(file, 64*1024, spoolFrameMaxSize)
+
+	var items []*pb.ClientData
+	for {
+		frame, err := reader.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("Failed to read spool file " + path + ": " + err.Error())
+		}
+
+		item, _, err := common.DecodeClientData(frame.Payload)
+		frame.Release()
+		if err != nil {
+			return nil, errors.New("Failed to read spool file " + path + ": " + err.Error())
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/disk"
+	"github.com/shirou/gopsutil/v4/mem"
+	"go.uber.org/zap"
+)
+
+// version identifies the client build in status reports. Release builds set it with
+// -ldflags "-X main.version=<version>".
+var version = "dev"
+
+// clientError is the last error the client ran into, reported in its status
+type clientError struct {
+	message string
+	at      time.Time
+}
+
+// recordError keeps err as the last error of the client
+func (qc *QuicClient) recordError(err error) {
+	qc.lastError.Store(&clientError{message: err.Error(), at: time.Now()})
+}
+
+// reportStatus sends a status report on a unidirectional stream right away and every heartbeat
+// interval after, until ctx ends or the connection closes
+func (qc *QuicClient) reportStatus(ctx context.Context, conn *quic.Conn) {
+	stream, err := conn.OpenUniStreamSync(ctx)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+
+	ticker := time.NewTicker(qc.config.Client.Heartbeat.Interval)
+	defer ticker.Stop()
+
+	for {
+		report := qc.status(ctx)
+		if err := common.WriteMessage(stream, common.FrameClientStatus, report); err != nil {
+			if ctx.Err() == nil && conn.Context().Err() == nil {
+				qc.logger.Warn("Failed to send status report", zap.Error(err))
+			}
+			return
+		}
+
+		qc.logger.Debug("Status report sent",
+			zap.Uint32("pool_items", report.PoolItems),
+			zap.Float64("cpu_percent", report.Host.CpuPercent))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-conn.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// status collects the current status of the client and its host
+func (qc *QuicClient) status(ctx context.Context) *pb.ClientStatus {
+	now := time.Now()
+
+	report := &pb.ClientStatus{
+		Version:         version,
+		StartedAt:       qc.startedAt.UnixMilli(),
+		SentAt:          now.UnixMilli(),
+		UptimeSeconds:   uint64(now.Sub(qc.startedAt).Seconds()),
+		PoolItems:       uint32(qc.dataPool.GetDataCount()),
+		PoolMaxItems:    uint32(qc.dataPool.GetMaxItems()),
+		DroppedItems:    qc.dataPool.GetDroppedCount(),
+		RejectedBatches: qc.rejectedBatches.Load(),
+		Host:            qc.hostStatus(ctx),
+	}
+
+	if qc.spool.Enabled() {
+		if size, err := qc.spool.Size(); err == nil {
+			report.SpoolBytes = uint64(size)
+		}
+	}
+
+	if last := qc.lastError.Load(); last != nil {
+		report.LastError = last.message
+		report.LastErrorAt = last.at.UnixMilli()
+	}
+
+	qc.settingsMu.Lock()
+	if qc.pushed != nil {
+		report.ConfigVersion = qc.pushed.Version
+	}
+	qc.settingsMu.Unlock()
+
+	return report
+}
+
+// hostStatus collects the CPU, memory and disk figures of the host. Figures the platform does not
+// provide are left out.
+func (qc *QuicClient) hostStatus(ctx context.Context) *pb.HostStatus {
+	host := &pb.HostStatus{DiskPath: qc.config.Client.Heartbeat.DiskPath}
+	host.Hostname, _ = os.Hostname()
+
+	// Without an interval the usage is measured since the previous call
+	if percents, err := cpu.PercentWithContext(ctx, 0, false); err != nil {
+		qc.logger.Debug("Failed to read CPU usage", zap.Error(err))
+	} else if len(percents) > 0 {
+		host.CpuPercent = percents[0]
+	}
+
+	if memory, err := mem.VirtualMemoryWithContext(ctx); err != nil {
+		qc.logger.Debug("Failed to read memory usage", zap.Error(err))
+	} else {
+		host.MemoryTotalBytes = memory.Total
+		host.MemoryUsedBytes = memory.Used
+	}
+
+	if usage, err := disk.UsageWithContext(ctx, host.DiskPath); err != nil {
+		qc.logger.Debug("Failed to read disk usage", zap.String("path", host.DiskPath), zap.Error(err))
+	} else {
+		host.DiskTotalBytes = usage.Total
+		host.DiskUsedBytes = usage.Used
+	}
+
+	return host
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// AdminServer serves the authenticated admin HTTP API:
+//
+//	GET  /health                    show server status
+//	GET  /connections               list live connections
+//	GET  /connections/{id}          show a connection
+//	POST /connections/{id}/close    close a connection, body {"code": 261, "reason": "..."} with a reconnectable code
+//	POST /clients/{client_id}/close close every connection of a client, same body
+//	POST /connections/{id}/control  send a control command, body {"command": "slow_down|flush|reconnect", "flush_interval": "5s", "duration": "1m", "endpoint": "host:port"}
+//	POST /clients/{client_id}/control send a control command to every connection of a client, same body
+//	GET  /ingestion                 show whether ingestion is paused
+//	POST /ingestion/pause           stop reading frames from clients
+//	POST /ingestion/resume          resume reading frames
+//	GET  /sinks                     show sink write status
+//	POST /drain                     drain every connection and refuse new ones, body {"endpoint": "host:port", "reason": "..."}
+type AdminServer struct {
+	quicServer   *QuicServer
+	sessions     *SessionRegistry
+	logger       *zap.Logger
+	token        []byte
+	drainTimeout time.Duration
+}
+
+// ConnectionView is the admin representation of a live connection
+type ConnectionView struct {
+	ID            string    `json:"id"`
+	ClientID      string    `json:"client_id"`
+	RemoteAddr    string    `json:"remote_addr"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	Streams       int64     `json:"streams"`
+	Frames        uint64    `json:"frames"`
+	Bytes         uint64    `json:"bytes"`
+	Records       uint64    `json:"records"`
+	Rejected      uint64    `json:"rejected"`
+	ConfigVersion uint64    `json:"config_version"`
+}
+
+type closeRequest struct {
+	Code   *uint64 `json:"code"`
+	Reason string  `json:"reason"`
+}
+
+type controlRequest struct {
+	Command       string `json:"command"`
+	FlushInterval string `json:"flush_interval"`
+	Duration      string `json:"duration"`
+	Endpoint      string `json:"endpoint"`
+	Reason        string `json:"reason"`
+}
+
+type controlView struct {
+	Connections int `json:"connections"`
+}
+
+// defaultSlowDownDuration applies to a slow down sent without a duration
+const defaultSlowDownDuration = time.Minute
+
+// HealthView summarizes the state of the server
+type HealthView struct {
+	Status      string           `json:"status"`
+	Instance    string           `json:"instance"`
+	Connections int              `json:"connections"`
+	Paused      bool             `json:"paused"`
+	Draining    bool             `json:"draining"`
+	Sinks       []SinkStatusView `json:"sinks"`
+}
+
+type drainRequest struct {
+	Endpoint string `json:"endpoint"`
+	Reason   string `json:"reason"`
+}
+
+type drainView struct {
+	Connections int `json:"connections"`
+}
+
+type ingestionView struct {
+	Paused bool `json:"paused"`
+}
+
+type errorView struct {
+	Error string `json:"error"`
+}
+
+func NewAdminServer(logger *zap.Logger, config *common.Config, quicServer *QuicServer, sessions *SessionRegistry, lifecycle fx.Lifecycle) *AdminServer {
+	admin := &AdminServer{
+		quicServer:   quicServer,
+		sessions:     sessions,
+		logger:       logger,
+		token:        []byte(config.Server.Admin.Token),
+		drainTimeout: config.Server.Drain.Timeout,
+	}
+
+	address := config.Server.Admin.ListeningAddress
+	if address == "" {
+		return admin
+	}
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           admin.routes(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return errors.New("Failed to start admin server: " + err.Error())
+			}
+
+			logger.Info("Starting admin server", zap.String("address", address))
+
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("Admin server stopped", zap.Error(err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping admin server")
+
+			return server.Shutdown(ctx)
+		},
+	})
+
+	return admin
+}
+
+func (a *AdminServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", a.getHealth)
+	mux.HandleFunc("GET /connections", a.listConnections)
+	mux.HandleFunc("GET /connections/{id}", a.getConnection)
+	mux.HandleFunc("POST /connections/{id}/close", a.closeConnection)
+	mux.HandleFunc("POST /clients/{client_id}/close", a.closeClient)
+	mux.HandleFunc("POST /connections/{id}/control", a.controlConnection)
+	mux.HandleFunc("POST /clients/{client_id}/control", a.controlClient)
+	mux.HandleFunc("GET /ingestion", a.getIngestion)
+	mux.HandleFunc("POST /ingestion/pause", a.pauseIngestion)
+	mux.HandleFunc("POST /ingestion/resume", a.resumeIngestion)
+	mux.HandleFunc("GET /sinks", a.listSinks)
+	mux.HandleFunc("POST /drain", a.drain)
+
+	return a.authenticate(mux)
+}
+
+// authenticate rejects requests without the configured bearer token
+func (a *AdminServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "Bearer "
+
+		header := r.Header.Get("Authorization")
+		if len(header) <= len(prefix) || header[:len(prefix)] != prefix ||
+			subtle.ConstantTimeCompare([]byte(header[len(prefix):]), a.token) != 1 {
+			writeJSON(w, http.StatusUnauthorized, errorView{Error: "unauthorized"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// getHealth reports "ok", "paused" while ingestion is paused, "draining" once a drain started, or
+// "degraded" when the last write of a sink failed
+func (a *AdminServer) getHealth(w http.ResponseWriter, r *http.Request) {
+	health := HealthView{
+		Status:      "ok",
+		Instance:    a.sessions.Instance(),
+		Connections: len(a.quicServer.Connections().List()),
+		Paused:      a.quicServer.Ingestion().Paused(),
+		Draining:    a.quicServer.Draining(),
+		Sinks:       a.quicServer.SinkStatus(),
+	}
+
+	for _, sink := range health.Sinks {
+		if sink.Failing {
+			health.Status = "degraded"
+		}
+	}
+	if health.Paused {
+		health.Status = "paused"
+	}
+	if health.Draining {
+		health.Status = "draining"
+	}
+
+	writeJSON(w, http.StatusOK, health)
+}
+
+func (a *AdminServer) listConnections(w http.ResponseWriter, r *http.Request) {
+	connections := a.quicServer.Connections().List()
+
+	views := make([]ConnectionView, 0, len(connections))
+	for _, connection := range connections {
+		views = append(views, newConnectionView(connection))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (a *AdminServer) getConnection(w http.ResponseWriter, r *http.Request) {
+	connection, ok := a.quicServer.Connections().Get(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorView{Error: "connection not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newConnectionView(connection))
+}
+
+func (a *AdminServer) closeConnection(w http.ResponseWriter, r *http.Request) {
+	connection, ok := a.quicServer.Connections().Get(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorView{Error: "connection not found"})
+		return
+	}
+
+	code, reason, err := readCloseRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorView{Error: err.Error()})
+		return
+	}
+
+	a.logger.Warn("Closing connection by admin request",
+		append(connection.LogFields(), zap.Uint64("code", uint64(code)), zap.String("reason", reason))...)
+
+	connection.Close(code, reason)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *AdminServer) closeClient(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+
+	code, reason, err := readCloseRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorView{Error: err.Error()})
+		return
+	}
+
+	closed := a.quicServer.Connections().CloseClient(clientID, code, reason)
+	if closed == 0 {
+		writeJSON(w, http.StatusNotFound, errorView{Error: "client not connected"})
+		return
+	}
+
+	a.logger.Warn("Closed client connections by admin request",
+		zap.String("client_id", clientID),
+		zap.Int("connections", closed),
+		zap.Uint64("code", uint64(code)),
+		zap.String("reason", reason))
+
+	writeJSON(w, http.StatusOK, map[string]int{"closed": closed})
+}
+
+func (a *AdminServer) controlConnection(w http.ResponseWriter, r *http.Request) {
+	connection, ok := a.quicServer.Connections().Get(r.PathValue("id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, errorView{Error: "connection not found"})
+		return
+	}
+
+	control, err := readControlRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorView{Error: err.Error()})
+		return
+	}
+
+	if connection.Send(common.FrameControl, control) == 0 {
+		writeJSON(w, http.StatusConflict, errorView{Error: "connection has no open stream"})
+		return
+	}
+
+	a.logger.Info("Sent control command by admin request",
+		append(connection.LogFields(), zap.Stringer("command", control.Command), zap.String("reason", control.Reason))...)
+
+	writeJSON(w, http.StatusOK, controlView{Connections: 1})
+}
+
+func (a *AdminServer) controlClient(w http.ResponseWriter, r *http.Request) {
+	clientID := r.PathValue("client_id")
+
+	control, err := readControlRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorView{Error: err.Error()})
+		return
+	}
+
+	sent := 0
+	for _, connection := range a.quicServer.Connections().List() {
+		if connection.ClientID == clientID && connection.Send(common.FrameControl, control) > 0 {
+			sent++
+		}
+	}
+	if sent == 0 {
+		writeJSON(w, http.StatusNotFound, errorView{Error: "client not connected"})
+		return
+	}
+
+	a.logger.Info("Sent control command by admin request",
+		zap.String("client_id", clientID),
+		zap.Int("connections", sent),
+		zap.Stringer("command", control.Command),
+		zap.String("reason", control.Reason))
+
+	writeJSON(w, http.StatusOK, controlView{Connections: sent})
+}
+
+func (a *AdminServer) getIngestion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ingestionView{Paused: a.quicServer.Ingestion().Paused()})
+}
+
+func (a *AdminServer) pauseIngestion(w http.ResponseWriter, r *http.Request) {
+	a.quicServer.Ingestion().Pause()
+	a.logger.Warn("Ingestion paused by admin request")
+
+	writeJSON(w, http.StatusOK, ingestionView{Paused: true})
+}
+
+func (a *AdminServer) resumeIngestion(w http.ResponseWriter, r *http.Request) {
+	a.quicServer.Ingestion().Resume()
+	a.logger.Info("Ingestion resumed by admin request")
+
+	writeJSON(w, http.StatusOK, ingestionView{Paused: false})
+}
+
+func (a *AdminServer) listSinks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.quicServer.SinkStatus())
+}
+
+// drain starts draining the server ahead of a restart. The drain runs in the background with the
+// configured timeout; new connections stay refused until the server restarts.
+func (a *AdminServer) drain(w http.ResponseWriter, r *http.Request) {
+	request := drainRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorView{Error: "invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	if request.Endpoint != "" {
+		if _, _, err := net.SplitHostPort(request.Endpoint); err != nil {
+			writeJSON(w, http.StatusBadRequest, errorView{Error: "invalid endpoint: " + err.Error()})
+			return
+		}
+	}
+
+	reason := request.Reason
+	if reason == "" {
+		reason = "drained by admin"
+	}
+
+	connections := len(a.quicServer.Connections().List())
+
+	a.logger.Warn("Draining server by admin request",
+		zap.String("redirect", request.Endpoint),
+		zap.String("reason", reason),
+		zap.Int("connections", connections))
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), a.drainTimeout)
+		defer cancel()
+
+		a.quicServer.Drain(ctx, request.Endpoint, reason)
+	}()
+
+	writeJSON(w, http.StatusAccepted, drainView{Connections: connections})
+}
+
+func newConnectionView(connection *Connection) ConnectionView {
+	return ConnectionView{
+		ID:            connection.ID,
+		ClientID:      connection.ClientID,
+		RemoteAddr:    connection.RemoteAddr,
+		ConnectedAt:   connection.ConnectedAt,
+		LastSeenAt:    time.UnixMilli(connection.Stats.LastSeenAt.Load()),
+		Streams:       connection.Stats.Streams.Load(),
+		Frames:        connection.Stats.Frames.Load(),
+		Bytes:         connection.Stats.Bytes.Load(),
+		Records:       connection.Stats.Records.Load(),
+		Rejected:      connection.Stats.Rejected.Load(),
+		ConfigVersion: connection.ConfigVersion(),
+	}
+}
+
+// adminCloseCodes are the codes an admin may close connections with. They all let the client
+// reconnect; a code such as auth_failed would stop it for good.
+var adminCloseCodes = []quic.ApplicationErrorCode{
+	common.ErrorCodeNoError,
+	common.ErrorCodeServerBusy,
+	common.ErrorCodeAdminClosed,
+	common.ErrorCodeDraining,
+}
+
+// readCloseRequest reads the optional close body, defaulting to ErrorCodeAdminClosed
+func readCloseRequest(r *http.Request) (quic.ApplicationErrorCode, string, error) {
+	request := closeRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			return 0, "", errors.New("invalid request body: " + err.Error())
+		}
+	}
+
+	code := common.ErrorCodeAdminClosed
+	if request.Code != nil {
+		code = quic.ApplicationErrorCode(*request.Code)
+		if !slices.Contains(adminCloseCodes, code) {
+			names := make([]string, 0, len(adminCloseCodes))
+			for _, allowed := range adminCloseCodes {
+				names = append(names, common.ErrorCodeName(allowed)+"="+strconv.FormatUint(uint64(allowed), 10))
+			}
+
+			return 0, "", errors.New("code " + common.ErrorCodeName(code) + " is not allowed, use one of " + strings.Join(names, ", "))
+		}
+	}
+
+	reason := request.Reason
+	if reason == "" {
+		reason = "closed by admin"
+	}
+
+	return code, reason, nil
+}
+
+// readControlRequest reads and validates a control command
+func readControlRequest(r *http.Request) (*pb.Control, error) {
+	request := controlRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, errors.New("invalid request body: " + err.Error())
+	}
+
+	control := &pb.Control{Reason: request.Reason}
+	if control.Reason == "" {
+		control.Reason = "sent by admin"
+	}
+
+	switch request.Command {
+	case "slow_down":
+		control.Command = pb.ControlCommand_CONTROL_COMMAND_SLOW_DOWN
+
+		interval, err := time.ParseDuration(request.FlushInterval)
+		if err != nil || interval <= 0 {
+			return nil, errors.New("invalid flush_interval: a positive duration is required")
+		}
+
+		duration := defaultSlowDownDuration
+		if request.Duration != "" {
+			duration, err = time.ParseDuration(request.Duration)
+			if err != nil || duration <= 0 {
+				return nil, errors.New("invalid duration: " + request.Duration)
+			}
+		}
+
+		control.FlushIntervalMs = uint32(interval.Milliseconds())
+		control.DurationMs = uint32(duration.Milliseconds())
+	case "flush":
+		control.Command = pb.ControlCommand_CONTROL_COMMAND_FLUSH
+	case "reconnect":
+		control.Command = pb.ControlCommand_CONTROL_COMMAND_RECONNECT
+
+		if request.Endpoint != "" {
+			if _, _, err := net.SplitHostPort(request.Endpoint); err != nil {
+				return nil, errors.New("invalid endpoint: " + err.Error())
+			}
+		}
+		control.Endpoint = request.Endpoint
+	default:
+		return nil, errors.New("unknown command " + strconv.Quote(request.Command) + ", expected slow_down, flush or reconnect")
+	}
+
+	return control, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
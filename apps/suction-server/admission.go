@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"go/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	AdmissionRejectedTotal  = "max_connections"
+	AdmissionRejectedSource = "max_connections_per_ip"
+	AdmissionRejectedRate   = "new_connections_rate"
+)
+
+// Admission decides whether an accepted connection may be served, capping the total number of
+// connections, the connections per source subnet and the rate of new connections
+type Admission struct {
+	mu        sync.Mutex
+	config    common.AdmissionConfig
+	total     int
+	perSource map[netip.Prefix]int
+	newConns  *rate.Limiter
+	active    prometheus.Gauge
+	rejected  *prometheus.CounterVec
+}
+
+func NewAdmission(config *common.Config, metrics *common.Metrics) (*Admission, error) {
+	admission := &Admission{
+		config:    config.Server.Admission,
+		perSource: make(map[netip.Prefix]int),
+		newConns:  rate.NewLimiter(rateOf(config.Server.Admission.NewConnectionsPerSecond), newConnectionsBurst(config.Server.Admission)),
+		active: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "connections_active",
+			Help:      "Connections currently admitted.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "connections_rejected_total",
+			Help:      "Connections rejected by admission control by reason.",
+		}, []string{"reason"}),
+	}
+
+	if err := metrics.Registry.Register(admission.active); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(admission.rejected); err != nil {
+		return nil, err
+	}
+
+	return admission, nil
+}
+
+// Reload applies changed caps. Connections already admitted are kept.
+func (a *Admission) Reload(event common.ReloadEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.config = event.Current.Server.Admission
+
+	now := time.Now()
+	a.newConns.SetLimitAt(now, rateOf(a.config.NewConnectionsPerSecond))
+	a.newConns.SetBurstAt(now, newConnectionsBurst(a.config))
+}
+
+// Admit reserves a slot for a connection from addr. On success the returned release function
+// must be called once the connection ends; otherwise the rejection reason is returned.
+func (a *Admission) Admit(addr net.Addr) (func(), string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	source := a.sourcePrefix(addr)
+
+	reason := ""
+	switch {
+	case a.config.MaxConnections > 0 && a.total >= a.config.MaxConnections:
+		reason = AdmissionRejectedTotal
+	case a.config.MaxConnectionsPerIP > 0 && source.IsValid() && a.perSource[source] >= a.config.MaxConnectionsPerIP:
+		reason = AdmissionRejectedSource
+	case !a.newConns.Allow():
+		reason = AdmissionRejectedRate
+	}
+
+	if reason != "" {
+		a.rejected.WithLabelValues(reason).Inc()
+		return nil, reason, false
+	}
+
+	a.total++
+	if source.IsValid() {
+		a.perSource[source]++
+	}
+	a.active.Inc()
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { a.release(source) })
+	}
+
+	return release, "", true
+}
+
+// newConnectionsBurst defaults an unset burst to one second worth of connections
+func newConnectionsBurst(config common.AdmissionConfig) int {
+	if config.NewConnectionsBurst > 0 {
+		return config.NewConnectionsBurst
+	}
+
+	return burstOf(config.NewConnectionsPerSecond)
+}
+
+func (a *Admission) release(source netip.Prefix) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.total--
+	if source.IsValid() {
+		if a.perSource[source] <= 1 {
+			delete(a.perSource, source)
+		} else {
+			a.perSource[source]--
+		}
+	}
+	a.active.Dec()
+}
+
+// sourcePrefix returns the subnet of addr used for per-IP limits. Callers must hold a.mu.
+func (a *Admission) sourcePrefix(addr net.Addr) netip.Prefix {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return netip.Prefix{}
+	}
+
+	ip := udpAddr.AddrPort().Addr().Unmap()
+	bits := a.config.IPv6PrefixLength
+	if ip.Is4() {
+		bits = a.config.IPv4PrefixLength
+	}
+
+	prefix, err := ip.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}
+	}
+
+	return prefix
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"go/common"
+	"go/pb"
+)
+
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// Authenticator verifies API keys and signed tokens against credentials stored in Redis.
+//
+// API keys are stored as SHA-256 hex digests in a hash mapping digest to client ID.
+// Tokens have the form "<key id>.<client id>.<expires unix>.<base64url HMAC-SHA256>", signed over
+// "<client id>.<expires unix>" with the secret stored under the key id in the signing keys hash.
+type Authenticator struct {
+	redisClient    *RedisClient
+	apiKeysKey     string
+	signingKeysKey string
+}
+
+func NewAuthenticator(config *common.Config, redisClient *RedisClient) *Authenticator {
+	return &Authenticator{
+		redisClient:    redisClient,
+		apiKeysKey:     config.Redis.AuthAPIKeysKey,
+		signingKeysKey: config.Redis.AuthSigningKeysKey,
+	}
+}
+
+// Authenticate returns the client ID owning the credential of the request
+func (a *Authenticator) Authenticate(ctx context.Context, request *pb.AuthRequest) (string, error) {
+	switch credential := request.Credential.(type) {
+	case *pb.AuthRequest_ApiKey:
+		return a.verifyAPIKey(ctx, credential.ApiKey)
+	case *pb.AuthRequest_Token:
+		return a.verifyToken(ctx, credential.Token)
+	default:
+		return "", ErrInvalidCredential
+	}
+}
+
+func (a *Authenticator) verifyAPIKey(ctx context.Context, apiKey string) (string, error) {
+	digest := sha256.Sum256([]byte(apiKey))
+
+	clientID, found, err := a.redisClient.HashGet(ctx, a.apiKeysKey, hex.EncodeToString(digest[:]))
+	if err != nil {
+		return "", err
+	}
+	if !found || clientID == "" {
+		return "", ErrInvalidCredential
+	}
+
+	return clientID, nil
+}
+
+func (a *Authenticator) verifyToken(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return "", ErrInvalidCredential
+	}
+	keyID, clientID, expires, signature := parts[0], parts[1], parts[2], parts[3]
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() >= expiresAt {
+		return "", ErrInvalidCredential
+	}
+
+	secret, found, err := a.redisClient.HashGet(ctx, a.signingKeysKey, keyID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", ErrInvalidCredential
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(clientID + "." + expires))
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), expected) {
+		return "", ErrInvalidCredential
+	}
+
+	return clientID, nil
+}
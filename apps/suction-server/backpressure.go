@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+// Backpressure asks the clients to send less while the ingest pipeline falls behind, which happens
+// when the sinks are slower than the clients. Every interval it samples the pipeline load. From the
+// high watermark on each client is sent a suggested rate below the rate it sent data at during the
+// last interval, so the rates keep falling until the load does. Between the watermarks the rates
+// are held, and once the load is back at the low watermark the clients are told the limit is lifted.
+type Backpressure struct {
+	logger     *zap.Logger
+	config     common.PressureConfig
+	quicServer *QuicServer
+	pipeline   *Pipeline
+	active     bool
+	received   map[string]uint64
+	done       chan struct{}
+
+	load    prometheus.GaugeFunc
+	engaged prometheus.Gauge
+	signals prometheus.Counter
+}
+
+func NewBackpressure(logger *zap.Logger, config *common.Config, quicServer *QuicServer, pipeline *Pipeline, metrics *common.Metrics, lifecycle fx.Lifecycle) (*Backpressure, error) {
+	backpressure := &Backpressure{
+		logger:     logger,
+		config:     config.Server.Backpressure,
+		quicServer: quicServer,
+		pipeline:   pipeline,
+		received:   make(map[string]uint64),
+		done:       make(chan struct{}),
+		load: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "pipeline_load_ratio",
+			Help:      "How full the fullest pipeline queue is, from 0 to 1.",
+		}, pipeline.Load),
+		engaged: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "backpressure_engaged",
+			Help:      "Whether clients are asked to send less (1) or not (0).",
+		}),
+		signals: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "backpressure_signals_total",
+			Help:      "Backpressure messages sent to clients.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{backpressure.load, backpressure.engaged, backpressure.signals} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	if backpressure.config.Disabled {
+		return backpressure, nil
+	}
+
+	var cancel context.CancelFunc
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			watchCtx, c := context.WithCancel(context.Background())
+			cancel = c
+
+			go backpressure.watch(watchCtx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			<-backpressure.done
+
+			return nil
+		},
+	})
+
+	return backpressure, nil
+}
+
+// watch samples the pipeline load every interval until ctx ends
+func (b *Backpressure) watch(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.update(b.pipeline.Load())
+		}
+	}
+}
+
+// update engages or releases the clients for the sampled load and refreshes their suggested rates
+func (b *Backpressure) update(load float64) {
+	connections := b.quicServer.Connections().List()
+	loadPercent := uint32(load * 100)
+
+	switch {
+	case !b.active && load >= b.config.HighWatermark:
+		b.active = true
+		b.engaged.Set(1)
+
+		b.logger.Warn("Ingest pipeline is falling behind, asking clients to send less",
+			zap.Float64("load", load),
+			zap.Int("connections", len(connections)))
+	case b.active && load <= b.config.LowWatermark:
+		b.active = false
+		b.engaged.Set(0)
+
+		b.logger.Info("Ingest pipeline caught up, lifting client rate limits",
+			zap.Float64("load", load),
+			zap.Int("connections", len(connections)))
+
+		b.send(connections, func(*Connection) *pb.Backpressure {
+			return &pb.Backpressure{LoadPercent: loadPercent}
+		})
+	}
+
+	received := make(map[string]uint64, len(connections))
+	for _, connection := range connections {
+		received[connection.ID] = connection.Stats.Bytes.Load()
+	}
+	previous := b.received
+	b.received = received
+
+	if !b.active {
+		return
+	}
+
+	// Above the high watermark the rate a client sent at shrinks by up to half, more the fuller the
+	// pipeline is; between the watermarks it is held. A connection seen for the first time gets its
+	// rate on the next interval.
+	scale := 1.0
+	if load >= b.config.HighWatermark {
+		scale = 1 - load/2
+	}
+	b.send(connections, func(connection *Connection) *pb.Backpressure {
+		last, ok := previous[connection.ID]
+		if !ok {
+			return nil
+		}
+
+		observed := float64(received[connection.ID]-last) / b.config.Interval.Seconds()
+
+		return &pb.Backpressure{
+			LoadPercent:             loadPercent,
+			SuggestedBytesPerSecond: max(b.config.MinRate.Uint64(), uint64(observed*scale)),
+		}
+	})
+}
+
+// send writes the message built for every connection, skipping connections without one
+func (b *Backpressure) send(connections []*Connection, message func(*Connection) *pb.Backpressure) {
+	for _, connection := range connections {
+		signal := message(connection)
+		if signal == nil {
+			continue
+		}
+
+		if connection.Send(common.FrameBackpressure, signal) > 0 {
+			b.signals.Inc()
+		}
+
+		b.logger.Debug("Sent backpressure to client", append(connection.LogFields(),
+			zap.Uint32("load_percent", signal.LoadPercent),
+			zap.Uint64("suggested_bytes_per_second", signal.SuggestedBytesPerSecond))...)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+)
+
+// configReadTimeout bounds the Redis reads loading the document of a client
+const configReadTimeout = 3 * time.Second
+
+// ConfigPusher pushes the configuration documents in Redis to the connected clients. The server
+// opens a control stream to a client once a document applies to it, writes the document whenever
+// its version changes and reads the acks of the client on the same stream.
+//
+// The document of a client is stored under "<prefix>:clients:<client id>" and replaces the
+// document of its group, "<prefix>:groups:<group>". The hash "<prefix>:client-groups" maps client
+// IDs to groups; other clients belong to the default group. Deleting a document pushes nothing,
+// so clients keep the settings last pushed to them until they restart without a cache file.
+type ConfigPusher struct {
+	redisClient     *RedisClient
+	logger          *zap.Logger
+	disabled        bool
+	keyPrefix       string
+	defaultGroup    string
+	refreshInterval time.Duration
+}
+
+func NewConfigPusher(logger *zap.Logger, config *common.Config, redisClient *RedisClient) *ConfigPusher {
+	return &ConfigPusher{
+		redisClient:     redisClient,
+		logger:          logger,
+		disabled:        config.Server.ConfigPush.Disabled,
+		keyPrefix:       config.Redis.ConfigKeyPrefix,
+		defaultGroup:    config.Server.ConfigPush.DefaultGroup,
+		refreshInterval: config.Server.ConfigPush.RefreshInterval,
+	}
+}
+
+// Push pushes the document of the client of a connection and every later version of it until the
+// connection closes
+func (p *ConfigPusher) Push(connection *Connection) {
+	if p.disabled {
+		return
+	}
+
+	ctx := connection.conn.Context()
+	logger := p.logger.With(connection.LogFields()...)
+
+	// The client identity is only final once the handshake completed
+	if err := connection.WaitHandshake(ctx); err != nil {
+		return
+	}
+
+	var (
+		stream *quic.Stream
+		pushed uint64
+	)
+
+	ticker := time.NewTicker(p.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		config, err := p.Load(ctx, connection.ClientID)
+		switch {
+		case err != nil:
+			logger.Warn("Failed to load client config", zap.Error(err))
+		case config != nil && config.Version != pushed:
+			if stream == nil {
+				if stream, err = connection.conn.OpenStreamSync(ctx); err != nil {
+					logger.Debug("Failed to open config stream", zap.Error(err))
+					return
+				}
+				defer stream.Close()
+
+				go p.readAcks(stream, connection, logger)
+			}
+
+			if err := common.WriteMessage(stream, common.FrameConfig, config); err != nil {
+				logger.Debug("Failed to push config", zap.Error(err))
+				return
+			}
+			pushed = config.Version
+
+			logger.Info("Pushed config to client", zap.Uint64("version", config.Version), zap.String("source", config.Source))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Load returns the document applying to a client, or nil when there is none
+func (p *ConfigPusher) Load(ctx context.Context, clientID string) (*pb.RemoteConfig, error) {
+	ctx, cancel := context.WithTimeout(ctx, configReadTimeout)
+	defer cancel()
+
+	group := p.defaultGroup
+	if clientID != "" {
+		source := "client:" + clientID
+		data, ok, err := p.redisClient.Get(ctx, common.ClientConfigKey(p.keyPrefix, clientID))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return common.ParseConfigDocument([]byte(data), source)
+		}
+
+		assigned, ok, err := p.redisClient.HashGet(ctx, common.ClientGroupsKey(p.keyPrefix), clientID)
+		if err != nil {
+			return nil, err
+		}
+		if ok && assigned != "" {
+			group = assigned
+		}
+	}
+
+	data, ok, err := p.redisClient.Get(ctx, common.GroupConfigKey(p.keyPrefix, group))
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	return common.ParseConfigDocument([]byte(data), "group:"+group)
+}
+
+// readAcks records the versions the client applied until the stream or connection closes
+func (p *ConfigPusher) readAcks(stream *quic.Stream, connection *Connection, logger *zap.Logger) {
+	reader := common.NewFrameReader(stream, common.FrameHeaderSize, common.ControlFrameMaxSize)
+
+	for {
+		ack := &pb.RemoteConfigAck{}
+		if err := common.ReadMessage(reader, common.FrameConfigAck, ack); err != nil {
+			if _, ok := common.ErrorCodeFromError(err); !ok && !errors.Is(err, io.EOF) && connection.conn.Context().Err() == nil {
+				logger.Warn("Failed to read config ack", zap.Error(err))
+			}
+			return
+		}
+
+		if !ack.Applied {
+			logger.Warn("Client did not apply pushed config", zap.Uint64("version", ack.Version), zap.String("message", ack.Message))
+			continue
+		}
+
+		connection.configVersion.Store(ack.Version)
+
+		logger.Debug("Client applied pushed config", zap.Uint64("version", ack.Version))
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// controlWriteTimeout bounds the write of a control frame to a client that stopped reading
+const controlWriteTimeout = 3 * time.Second
+
+type connectionKey struct{}
+
+// Connection describes an accepted client connection and its identity
+type Connection struct {
+	ID          string
+	ClientID    string
+	RemoteAddr  string
+	ConnectedAt time.Time
+	Stats       ConnectionStats
+	conn        *quic.Conn
+	violations  violationCounter
+
+	mu      sync.Mutex
+	writers map[*ackWriter]struct{}
+	drain   *pb.Drain
+	drained chan struct{}
+
+	// configVersion is the version of the pushed config the client last applied
+	configVersion atomic.Uint64
+}
+
+// ConnectionStats counts the data frames received on a connection
+type ConnectionStats struct {
+	Streams    atomic.Int64
+	Frames     atomic.Uint64
+	Bytes      atomic.Uint64
+	Records    atomic.Uint64
+	Rejected   atomic.Uint64
+	LastSeenAt atomic.Int64
+}
+
+// Observe counts a received data frame of the given wire size
+func (s *ConnectionStats) Observe(bytes int) {
+	s.Frames.Add(1)
+	s.Bytes.Add(uint64(bytes))
+	s.LastSeenAt.Store(time.Now().UnixMilli())
+}
+
+func newConnection(conn *quic.Conn, clientID string) *Connection {
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	connection := &Connection{
+		ID:          hex.EncodeToString(id),
+		ClientID:    clientID,
+		RemoteAddr:  conn.RemoteAddr().String(),
+		ConnectedAt: time.Now(),
+		conn:        conn,
+		writers:     make(map[*ackWriter]struct{}),
+		drained:     make(chan struct{}),
+	}
+	connection.Stats.LastSeenAt.Store(connection.ConnectedAt.UnixMilli())
+
+	return connection
+}
+
+// LogFields returns the fields identifying the connection in logs
+func (c *Connection) LogFields() []zap.Field {
+	return []zap.Field{
+		zap.String("connection_id", c.ID),
+		zap.String("client_id", c.ClientID),
+		zap.String("remote", c.RemoteAddr),
+	}
+}
+
+// ConfigVersion returns the version of the pushed config the client last applied, 0 when none
+func (c *Connection) ConfigVersion() uint64 {
+	return c.configVersion.Load()
+}
+
+// Close closes the connection with an application error code
+func (c *Connection) Close(code quic.ApplicationErrorCode, reason string) error {
+	return c.conn.CloseWithError(code, reason)
+}
+
+// WaitHandshake waits until the handshake of the connection completed, so data it carried
+// can no longer be a replay of 0-RTT data
+func (c *Connection) WaitHandshake(ctx context.Context) error {
+	select {
+	case <-c.conn.HandshakeComplete():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attach registers the writer of a stream, so control frames reach the client on it. A stream
+// opened while the connection drains is told to drain right away.
+func (c *Connection) attach(writer *ackWriter) {
+	c.mu.Lock()
+	c.writers[writer] = struct{}{}
+	drain := c.drain
+	c.mu.Unlock()
+
+	if drain != nil {
+		writer.WriteControl(common.FrameDrain, drain)
+	}
+}
+
+// detach removes the writer of a stream once its last ack was written
+func (c *Connection) detach(writer *ackWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.writers, writer)
+	if c.drain != nil && len(c.writers) == 0 {
+		c.closeDrained()
+	}
+}
+
+// Send writes a frame to the client on every stream and returns the number of streams it reached
+func (c *Connection) Send(frameType common.FrameType, message proto.Message) int {
+	sent := 0
+	for _, writer := range c.streamWriters() {
+		if err := writer.WriteControl(frameType, message); err == nil {
+			sent++
+		}
+	}
+
+	return sent
+}
+
+// streamWriters returns the writers of the streams attached at the time of the call
+func (c *Connection) streamWriters() []*ackWriter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return slices.Collect(maps.Keys(c.writers))
+}
+
+// Drain tells the client on every stream to stop sending and reconnect, and waits until the client
+// closed its streams, their acks were written and the client closed the connection. The connection
+// is closed without waiting any longer when ctx ends first.
+func (c *Connection) Drain(ctx context.Context, drain *pb.Drain) {
+	// Streams attached after the drain was set are told by attach, so each stream is told once
+	c.mu.Lock()
+	var writers []*ackWriter
+	if c.drain == nil {
+		c.drain = drain
+		writers = slices.Collect(maps.Keys(c.writers))
+		if len(writers) == 0 {
+			c.closeDrained()
+		}
+	}
+	streams := len(c.writers)
+	c.mu.Unlock()
+
+	for _, writer := range writers {
+		writer.WriteControl(common.FrameDrain, drain)
+	}
+
+	select {
+	case <-c.drained:
+	case <-ctx.Done():
+	case <-c.conn.Context().Done():
+	}
+
+	// Closing the connection drops stream data not yet sent, so the client closes it once it read the last acks
+	if streams > 0 {
+		select {
+		case <-ctx.Done():
+		case <-c.conn.Context().Done():
+		}
+	}
+
+	c.conn.CloseWithError(common.ErrorCodeDraining, "server draining")
+}
+
+// closeDrained marks the drain as complete. Callers must hold c.mu.
+func (c *Connection) closeDrained() {
+	select {
+	case <-c.drained:
+	default:
+		close(c.drained)
+	}
+}
+
+func withConnection(ctx context.Context, connection *Connection) context.Context {
+	return context.WithValue(ctx, connectionKey{}, connection)
+}
+
+// ConnectionFromContext returns the connection attached to the context
+func ConnectionFromContext(ctx context.Context) (*Connection, bool) {
+	connection, ok := ctx.Value(connectionKey{}).(*Connection)
+	return connection, ok
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ConnectionRegistry keeps the live connections of the server
+type ConnectionRegistry struct {
+	mu          sync.RWMutex
+	connections map[string]*Connection
+}
+
+func newConnectionRegistry() *ConnectionRegistry {
+	return &ConnectionRegistry{connections: make(map[string]*Connection)}
+}
+
+func (r *ConnectionRegistry) add(connection *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.connections[connection.ID] = connection
+}
+
+func (r *ConnectionRegistry) remove(connection *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.connections, connection.ID)
+}
+
+// List returns the live connections ordered by connect time
+func (r *ConnectionRegistry) List() []*Connection {
+	r.mu.RLock()
+	connections := make([]*Connection, 0, len(r.connections))
+	for _, connection := range r.connections {
+		connections = append(connections, connection)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ConnectedAt.Before(connections[j].ConnectedAt)
+	})
+
+	return connections
+}
+
+// Get returns the live connection with the given ID
+func (r *ConnectionRegistry) Get(id string) (*Connection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	connection, ok := r.connections[id]
+	return connection, ok
+}
+
+// CloseClient closes every connection of a client and returns how many were closed
+func (r *ConnectionRegistry) CloseClient(clientID string, code quic.ApplicationErrorCode, reason string) int {
+	closed := 0
+	for _, connection := range r.List() {
+		if connection.ClientID == clientID {
+			connection.Close(code, reason)
+			closed++
+		}
+	}
+
+	return closed
+}
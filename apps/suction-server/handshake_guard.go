@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"go/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/quic-go/quic-go"
+	"golang.org/x/time/rate"
+)
+
+const (
+	RetryNever     = "never"
+	RetryUnderLoad = "under_load"
+	RetryAlways    = "always"
+
+	handshakeIdleExpiry = 10 * time.Minute
+)
+
+var (
+	errHandshakeRate      = errors.New("handshake rate exceeded")
+	errHandshakeRatePerIP = errors.New("handshake rate per IP exceeded")
+)
+
+// HandshakeGuard applies source address validation and handshake rate limits to the QUIC
+// transport, before any connection state or TLS work is spent on the attempt
+type HandshakeGuard struct {
+	mu          sync.Mutex
+	config      common.HandshakeConfig
+	unvalidated *rate.Limiter
+	handshakes  *rate.Limiter
+	perIP       map[netip.Addr]*handshakeLimiter
+	lastSweep   time.Time
+	retries     prometheus.Counter
+	rejected    *prometheus.CounterVec
+}
+
+type handshakeLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func NewHandshakeGuard(config *common.Config, metrics *common.Metrics) (*HandshakeGuard, error) {
+	handshake := config.Server.Handshake
+	guard := &HandshakeGuard{
+		config:      handshake,
+		unvalidated: rate.NewLimiter(rateOf(handshake.RetryThreshold), burstOf(handshake.RetryThreshold)),
+		handshakes:  rate.NewLimiter(rateOf(handshake.MaxHandshakesPerSecond), burstOf(handshake.MaxHandshakesPerSecond)),
+		perIP:       make(map[netip.Addr]*handshakeLimiter),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "handshake_retries_total",
+			Help:      "Connection attempts asked to validate their address with a Retry packet.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "handshakes_rejected_total",
+			Help:      "Connection attempts refused by handshake rate limits by reason.",
+		}, []string{"reason"}),
+	}
+
+	if err := metrics.Registry.Register(guard.retries); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(guard.rejected); err != nil {
+		return nil, err
+	}
+
+	return guard, nil
+}
+
+// Reload applies changed policies and rates
+func (g *HandshakeGuard) Reload(event common.ReloadEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.config = event.Current.Server.Handshake
+
+	now := time.Now()
+	g.unvalidated.SetLimitAt(now, rateOf(g.config.RetryThreshold))
+	g.unvalidated.SetBurstAt(now, burstOf(g.config.RetryThreshold))
+	g.handshakes.SetLimitAt(now, rateOf(g.config.MaxHandshakesPerSecond))
+	g.handshakes.SetBurstAt(now, burstOf(g.config.MaxHandshakesPerSecond))
+	clear(g.perIP)
+}
+
+// VerifySourceAddress reports whether an attempt without a valid token must first complete a Retry
+func (g *HandshakeGuard) VerifySourceAddress(addr net.Addr) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	retry := false
+	switch g.config.Retry {
+	case RetryAlways:
+		retry = true
+	case RetryUnderLoad:
+		retry = !g.unvalidated.Allow()
+	}
+
+	if retry {
+		g.retries.Inc()
+	}
+
+	return retry
+}
+
+// ConnContext refuses connection attempts above the handshake rate limits
+func (g *HandshakeGuard) ConnContext(ctx context.Context, info *quic.ClientInfo) (context.Context, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+
+	if info.AddrVerified && g.config.MaxHandshakesPerSecondPerIP > 0 {
+		if !g.limiterFor(info.RemoteAddr, now).AllowN(now, 1) {
+			g.rejected.WithLabelValues("per_ip").Inc()
+			return ctx, errHandshakeRatePerIP
+		}
+	}
+
+	if !g.handshakes.AllowN(now, 1) {
+		g.rejected.WithLabelValues("global").Inc()
+		return ctx, errHandshakeRate
+	}
+
+	return ctx, nil
+}
+
+// limiterFor returns the handshake limiter of a validated address. Callers must hold g.mu.
+func (g *HandshakeGuard) limiterFor(addr net.Addr, now time.Time) *rate.Limiter {
+	g.sweep(now)
+
+	ip := netip.Addr{}
+	if udpAddr, ok := addr.(*net.UDPAddr); ok {
+		ip = udpAddr.AddrPort().Addr().Unmap()
+	}
+
+	entry, ok := g.perIP[ip]
+	if !ok {
+		perSecond := g.config.MaxHandshakesPerSecondPerIP
+		entry = &handshakeLimiter{limiter: rate.NewLimiter(rateOf(perSecond), burstOf(perSecond))}
+		g.perIP[ip] = entry
+	}
+	entry.lastSeen = now
+
+	return entry.limiter
+}
+
+// sweep drops limiters of addresses without recent handshakes, at most once per minute
+func (g *HandshakeGuard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < time.Minute {
+		return
+	}
+	g.lastSweep = now
+
+	for ip, entry := range g.perIP {
+		if now.Sub(entry.lastSeen) > handshakeIdleExpiry {
+			delete(g.perIP, ip)
+		}
+	}
+}
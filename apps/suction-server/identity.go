@@ -0,0 +1,36 @@
+package main
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+const (
+	ClientIDSourceCommonName = "cn"
+	ClientIDSourceSAN        = "san"
+)
+
+// clientIDFromCertificate derives the client ID from the verified client certificate.
+// The SAN source prefers URI, then DNS, then email entries.
+func clientIDFromCertificate(certificate *x509.Certificate, source string) (string, error) {
+	switch source {
+	case ClientIDSourceSAN:
+		for _, uri := range certificate.URIs {
+			return uri.String(), nil
+		}
+		for _, dnsName := range certificate.DNSNames {
+			return dnsName, nil
+		}
+		for _, email := range certificate.EmailAddresses {
+			return email, nil
+		}
+
+		return "", errors.New("client certificate has no subject alternative name")
+	default:
+		if certificate.Subject.CommonName == "" {
+			return "", errors.New("client certificate has no common name")
+		}
+
+		return certificate.Subject.CommonName, nil
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// IngestionGate pauses the stream readers. While paused no frames are read, so QUIC flow
+// control pushes back on clients instead of data being dropped.
+type IngestionGate struct {
+	mu      sync.Mutex
+	resumed chan struct{}
+}
+
+func newIngestionGate() *IngestionGate {
+	resumed := make(chan struct{})
+	close(resumed)
+
+	return &IngestionGate{resumed: resumed}
+}
+
+// Pause stops ingestion until Resume is called
+func (g *IngestionGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+		g.resumed = make(chan struct{})
+	default:
+	}
+}
+
+// Resume releases the waiting readers
+func (g *IngestionGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+	default:
+		close(g.resumed)
+	}
+}
+
+// Paused reports whether ingestion is paused
+func (g *IngestionGate) Paused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+// Wait blocks while ingestion is paused
+func (g *IngestionGate) Wait(ctx context.Context) error {
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -12,10 +12,15 @@ import (
 func main() {
 	app := fx.New(
 		common.Module,
-		fx.Provide(NewQuicServer, NewRedisClient),
-		fx.Invoke(func(lc fx.Lifecycle, logger *zap.Logger, quicServer *QuicServer) {
+		fx.Provide(NewQuicServer, NewPipeline, NewRedisClient, NewSinks, NewDeadLetterStream, NewAuthenticator, NewRateLimiter, NewAdmission, NewHandshakeGuard, NewTicketKeys, NewSessionRegistry, NewConfigPusher, NewBackpressure, NewStatusRegistry, NewAdminServer),
+		common.ProvideReloadable[*RateLimiter](),
+		common.ProvideReloadable[*Admission](),
+		common.ProvideReloadable[*HandshakeGuard](),
+		fx.Invoke(func(lc fx.Lifecycle, logger *zap.Logger, config *common.Config, metrics *common.Metrics, quicServer *QuicServer, adminServer *AdminServer, backpressure *Backpressure) {
 			logger.Info("Starting application")
 
+			metrics.Serve(lc, logger, config.Server.MetricsListeningAddress)
+
 			lc.Append(fx.Hook{
 				OnStop: func(ctx context.Context) error {
 					logger.Info("Stopping application")
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/quic-go/quic-go"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	PipelineStageDecode = "decode"
+	PipelineStageSink   = "sink"
+)
+
+// defaultDecodedSizeFactor bounds the decompressed payload of a data frame relative to max_frame_size
+// when max_decoded_size is not set
+const defaultDecodedSizeFactor = 4
+
+var errPipelineStopped = errors.New("pipeline stopped")
+
+// errWriterClosed is returned for frames written after the send side of the stream was closed or reset
+var errWriterClosed = errors.New("stream closed for writing")
+
+// Pipeline decodes data frames and writes the records to the sinks in stages, so CPU and memory
+// use are bounded by the worker counts and queue sizes rather than by the number of streams:
+//
+//	stream readers -> decode queue -> decode workers -> sink queue -> sink workers
+//
+// Enqueueing blocks while a queue is full, which stops the stream reader and lets QUIC flow
+// control push back on the client. Acks are written once a record reached the sinks.
+type Pipeline struct {
+	mu            sync.RWMutex
+	stopped       bool
+	logger        *zap.Logger
+	sinks         []Sink
+	sinkStatus    []*SinkStatus
+	deadLetters   *DeadLetterStream
+	rateLimiter   *RateLimiter
+	decodeQueue   chan *frameJob
+	sinkQueue     chan *recordJob
+	decodeWorkers int
+	sinkWorkers   int
+	maxDecoded    int
+	done          chan struct{}
+	backpressure  *prometheus.CounterVec
+}
+
+// frameJob is a data frame waiting to be decoded
+type frameJob struct {
+	ctx        context.Context
+	connection *Connection
+	stream     *ackWriter
+	frame      *common.Frame
+	receivedAt time.Time
+}
+
+// recordJob is a decoded record waiting to be written to the sinks
+type recordJob struct {
+	ctx        context.Context
+	connection *Connection
+	stream     *ackWriter
+	record     *Record
+	raw        *[]byte
+}
+
+func NewPipeline(logger *zap.Logger, config *common.Config, sinks []Sink, deadLetters *DeadLetterStream, rateLimiter *RateLimiter, metrics *common.Metrics, lifecycle fx.Lifecycle) (*Pipeline, error) {
+	decodeWorkers := config.Server.Pipeline.DecodeWorkers
+	if decodeWorkers == 0 {
+		decodeWorkers = runtime.GOMAXPROCS(0)
+	}
+	maxDecoded := config.Server.MaxDecodedSize.Int()
+	if maxDecoded == 0 {
+		maxDecoded = defaultDecodedSizeFactor * config.Server.MaxFrameSize.Int()
+	}
+
+	pipeline := &Pipeline{
+		logger:        logger,
+		sinks:         sinks,
+		deadLetters:   deadLetters,
+		rateLimiter:   rateLimiter,
+		decodeQueue:   make(chan *frameJob, config.Server.Pipeline.DecodeQueueSize),
+		sinkQueue:     make(chan *recordJob, config.Server.Pipeline.SinkQueueSize),
+		decodeWorkers: decodeWorkers,
+		sinkWorkers:   config.Server.Pipeline.SinkWorkers,
+		maxDecoded:    maxDecoded,
+		done:          make(chan struct{}),
+		backpressure: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "pipeline_backpressure_total",
+			Help:      "Jobs that had to wait for room in a full pipeline queue by stage.",
+		}, []string{"stage"}),
+	}
+
+	for _, sink := range sinks {
+		pipeline.sinkStatus = append(pipeline.sinkStatus, newSinkStatus(sink.Name()))
+	}
+
+	collectors := []prometheus.Collector{
+		pipeline.backpressure,
+		newQueueDepthGauge(PipelineStageDecode, func() int { return len(pipeline.decodeQueue) }),
+		newQueueDepthGauge(PipelineStageSink, func() int { return len(pipeline.sinkQueue) }),
+	}
+	for _, collector := range collectors {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			logger.Info("Starting ingest pipeline",
+				zap.Int("decode_workers", pipeline.decodeWorkers),
+				zap.Int("decode_queue_size", cap(pipeline.decodeQueue)),
+				zap.Int("sink_workers", pipeline.sinkWorkers),
+				zap.Int("sink_queue_size", cap(pipeline.sinkQueue)))
+
+			pipeline.start()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping ingest pipeline")
+
+			return pipeline.stop(ctx)
+		},
+	})
+
+	return pipeline, nil
+}
+
+func newQueueDepthGauge(stage string, depth func() int) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace:   "suction",
+		Subsystem:   "server",
+		Name:        "pipeline_queue_depth",
+		Help:        "Jobs waiting in a pipeline queue by stage.",
+		ConstLabels: prometheus.Labels{"stage": stage},
+	}, func() float64 {
+		return float64(depth())
+	})
+}
+
+// Submit queues a data frame for decoding, blocking while the decode queue is full.
+// It fails when ctx ends first or the pipeline is stopping.
+func (p *Pipeline) Submit(ctx context.Context, job *frameJob) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return errPipelineStopped
+	}
+
+	job.stream.pending.Add(1)
+
+	select {
+	case p.decodeQueue <- job:
+		return nil
+	default:
+	}
+
+	p.backpressure.WithLabelValues(PipelineStageDecode).Inc()
+
+	select {
+	case p.decodeQueue <- job:
+		return nil
+	case <-ctx.Done():
+		job.stream.pending.Done()
+		return ctx.Err()
+	}
+}
+
+// Load returns how full the fullest queue is, from 0 for empty queues to 1 when one is full
+func (p *Pipeline) Load() float64 {
+	return max(
+		float64(len(p.decodeQueue))/float64(cap(p.decodeQueue)),
+		float64(len(p.sinkQueue))/float64(cap(p.sinkQueue)),
+	)
+}
+
+// SinkStatus returns the write status of every sink
+func (p *Pipeline) SinkStatus() []SinkStatusView {
+	views := make([]SinkStatusView, 0, len(p.sinkStatus))
+	for _, status := range p.sinkStatus {
+		views = append(views, status.View())
+	}
+
+	return views
+}
+
+// start runs the workers. The sink queue is closed once every decode worker is done,
+// so records decoded before a stop still reach the sinks.
+func (p *Pipeline) start() {
+	var decoders, writers sync.WaitGroup
+
+	for range p.decodeWorkers {
+		decoders.Add(1)
+		go func() {
+			defer decoders.Done()
+
+			for job := range p.decodeQueue {
+				p.decode(job)
+			}
+		}()
+	}
+
+	for range p.sinkWorkers {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+
+			for job := range p.sinkQueue {
+				p.write(job)
+			}
+		}()
+	}
+
+	go func() {
+		decoders.Wait()
+		close(p.sinkQueue)
+		writers.Wait()
+		close(p.done)
+	}()
+}
+
+// stop refuses new frames and waits until the queued ones are written or ctx ends. Sinks write
+// synchronously, so every record is stored once stop returns nil.
+func (p *Pipeline) stop(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.stopped {
+		p.stopped = true
+		close(p.decodeQueue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return errors.New("Pipeline did not drain: " + ctx.Err().Error())
+	}
+
+	p.logger.Info("Ingest pipeline drained")
+
+	return nil
+}
+
+func (p *Pipeline) decode(job *frameJob) {
+	connection := job.connection
+	compressedData := job.frame.Payload
+	defer job.frame.Release()
+
+	// Decompress and unmarshal data
+	clientData, raw, err := common.DecodeClientDataPooled(compressedData, p.maxDecoded)
+	if err != nil {
+		p.logger.Error("Failed to decode client data", zap.String("client_id", connection.ClientID), zap.Error(err))
+		connection.Stats.Rejected.Add(1)
+
+		// The sequence is inside the frame that failed to decode, so the client cannot be acked
+		code := common.ErrorCodeProtocolViolation
+		if errors.Is(err, common.ErrFrameTooLarge) {
+			code = common.ErrorCodeFrameTooLarge
+		}
+		p.fail(job.stream, code, err.Error())
+		return
+	}
+	decompressedData := *raw
+
+	// Calculate compression statistics
+	compressionRatio := float64(len(compressedData)) / float64(len(decompressedData)) * 100
+	sizeReduction := len(decompressedData) - len(compressedData)
+
+	p.logger.Info("Received snappy compressed protobuf message",
+		zap.String("connection_id", connection.ID),
+		zap.String("client_id", connection.ClientID),
+		zap.Uint64("sequence", clientData.Sequence),
+		zap.Int64("timestamp", clientData.Timestamp),
+		zap.Int("message_length", len(clientData.Message)),
+		zap.Int("sensor_readings_count", len(clientData.SensorReadings)),
+		zap.Int("original_size", len(decompressedData)),
+		zap.Int("compressed_size", len(compressedData)),
+		zap.Float64("compression_ratio_percent", compressionRatio),
+		zap.Int("size_reduction_bytes", sizeReduction),
+		zap.Uint64("stream_id", uint64(job.stream.StreamID())))
+
+	// Limits are checked after decoding so the client learns which sequence to retry,
+	// and count the bytes received on the wire
+	if decision := p.rateLimiter.Allow(job.ctx, connection, len(compressedData)); decision != RateAllowed {
+		connection.Stats.Rejected.Add(1)
+
+		retryAfter := p.rateLimiter.RetryAfter(connection, decision, len(compressedData), time.Now())
+		p.ack(job.stream, &pb.Ack{
+			Sequence:     clientData.Sequence,
+			Status:       decision.AckStatus(),
+			Message:      decision.String(),
+			RetryAfterMs: uint32(min(retryAfter.Milliseconds(), math.MaxUint32)),
+		})
+		common.ReleaseClientData(clientData)
+		common.Buffers.Put(raw)
+
+		if p.rateLimiter.RecordViolation(connection) {
+			p.logger.Warn("Closing connection after repeated rate limit violations", connection.LogFields()...)
+			connection.Close(common.ErrorCodeRateLimited, "rate limit exceeded")
+		}
+
+		return
+	}
+
+	record := &recordJob{
+		ctx:        job.ctx,
+		connection: connection,
+		stream:     job.stream,
+		record: &Record{
+			ConnectionID: connection.ID,
+			ClientID:     connection.ClientID,
+			RemoteAddr:   connection.RemoteAddr,
+			StreamID:     int64(job.stream.StreamID()),
+			ReceivedAt:   job.receivedAt,
+			Payload:      decompressedData,
+			Data:         clientData,
+		},
+		raw: raw,
+	}
+
+	// The sink queue outlives the decode workers, so a blocking send cannot hit a closed channel
+	select {
+	case p.sinkQueue <- record:
+	default:
+		p.backpressure.WithLabelValues(PipelineStageSink).Inc()
+		p.sinkQueue <- record
+	}
+}
+
+// write stores a record even when its connection is gone, as the frame was already received.
+// The record buffers go back to the pools once the sinks are done with them.
+func (p *Pipeline) write(job *recordJob) {
+	p.writeToSinks(context.WithoutCancel(job.ctx), job.record)
+	job.connection.Stats.Records.Add(1)
+
+	sequence := job.record.Data.Sequence
+	common.ReleaseClientData(job.record.Data)
+	common.Buffers.Put(job.raw)
+
+	p.ack(job.stream, &pb.Ack{Sequence: sequence, Status: pb.AckStatus_ACK_STATUS_OK})
+}
+
+// ack writes the result of a job to its stream and marks the job as done
+func (p *Pipeline) ack(stream *ackWriter, ack *pb.Ack) {
+	defer stream.pending.Done()
+
+	if err := stream.WriteMessage(common.FrameAck, ack); err != nil {
+		p.logger.Debug("Failed to write ack", zap.Uint64("stream_id", uint64(stream.StreamID())), zap.Error(err))
+	}
+}
+
+// fail reports a frame that could not be handled to its stream and marks the job as done
+func (p *Pipeline) fail(stream *ackWriter, code quic.ApplicationErrorCode, message string) {
+	defer stream.pending.Done()
+
+	if err := stream.WriteMessage(common.FrameError, &pb.Error{Code: uint64(code), Message: message}); err != nil {
+		p.logger.Debug("Failed to write error", zap.Uint64("stream_id", uint64(stream.StreamID())), zap.Error(err))
+	}
+}
+
+func (p *Pipeline) writeToSinks(ctx context.Context, record *Record) {
+	for i, sink := range p.sinks {
+		err := sink.Write(ctx, record)
+		p.sinkStatus[i].observe(err)
+
+		if err != nil {
+			p.logger.Error("Failed to write record to sink",
+				zap.String("sink", sink.Name()),
+				zap.String("client_id", record.ClientID),
+				zap.Error(err))
+
+			if err := p.deadLetters.Write(ctx, record, sink.Name(), err); err != nil {
+				p.logger.Error("Failed to write record to dead letter stream", zap.String("sink", sink.Name()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// ackWriter serializes the acks written to a stream by concurrent workers and tracks the
+// frames of the stream still in the pipeline. Once the send side was closed or reset, writes fail
+// with errWriterClosed.
+type ackWriter struct {
+	mu      sync.Mutex
+	stream  *quic.Stream
+	pending sync.WaitGroup
+	aborted bool
+	code    quic.ApplicationErrorCode
+	closed  bool
+}
+
+func newAckWriter(stream *quic.Stream) *ackWriter {
+	return &ackWriter{stream: stream}
+}
+
+func (w *ackWriter) StreamID() quic.StreamID {
+	return w.stream.StreamID()
+}
+
+// WriteMessage writes a frame to the client, serialized with the acks written by the sink workers
+func (w *ackWriter) WriteMessage(frameType common.FrameType, message proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	return common.WriteMessage(w.stream, frameType, message)
+}
+
+// WriteControl writes a frame the client did not ask for. The write gives up after
+// controlWriteTimeout; the frame may then be cut off, so the stream is reset instead of carrying
+// the rest of it.
+func (w *ackWriter) WriteControl(frameType common.FrameType, message proto.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	w.stream.SetWriteDeadline(time.Now().Add(controlWriteTimeout))
+	defer w.stream.SetWriteDeadline(time.Time{})
+
+	err := common.WriteMessage(w.stream, frameType, message)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		w.stream.CancelWrite(quic.StreamErrorCode(common.ErrorCodeInternal))
+		w.closed = true
+	}
+
+	return err
+}
+
+// Abort stops reading the stream with the code. The stream is reset with it once the acks of the
+// frames still in the pipeline are written.
+func (w *ackWriter) Abort(code quic.ApplicationErrorCode) {
+	w.stream.CancelRead(quic.StreamErrorCode(code))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.aborted = true
+	w.code = code
+}
+
+// Close waits for the acks of the frames still in the pipeline, then closes the send side of the
+// stream, or resets it when the stream was aborted. A send side already reset stays as it is.
+func (w *ackWriter) Close() error {
+	w.pending.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.aborted {
+		w.stream.CancelWrite(quic.StreamErrorCode(w.code))
+		return nil
+	}
+
+	return w.stream.Close()
+}
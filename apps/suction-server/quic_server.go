@@ -3,71 +3,155 @@ package main
 import (
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go/common"
 	"go/pb"
 
-	"github.com/golang/snappy"
 	"github.com/quic-go/quic-go"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
-	"google.golang.org/protobuf/proto"
 )
 
 type QuicServer struct {
-	listener   *quic.Listener
-	logger     *zap.Logger
-	BufferSize int
+	transport      *quic.Transport
+	listener       *quic.EarlyListener
+	logger         *zap.Logger
+	pipeline       *Pipeline
+	connections    *ConnectionRegistry
+	ingestion      *IngestionGate
+	authenticator  *Authenticator
+	admission      *Admission
+	sessions       *SessionRegistry
+	configPusher   *ConfigPusher
+	statuses       *StatusRegistry
+	mutualTLS      bool
+	clientIDSource string
+	tokenRequired  bool
+	authTimeout    time.Duration
+	maxFrameSize   int
+	drainTimeout   time.Duration
+	drainRedirect  string
+	draining       atomic.Bool
+	BufferSize     int
+
+	// handlers tracks the accept loop and the goroutines it starts, so shutdown waits for the frames
+	// being read before the pipeline stops. ctx ends once shutdown stops waiting for them.
+	handlers sync.WaitGroup
+	ctx      context.Context
 }
 
-func NewQuicServer(logger *zap.Logger, config *common.Config, tls *common.Tls, lifecycle fx.Lifecycle) (*QuicServer, error) {
+func NewQuicServer(logger *zap.Logger, config *common.Config, tls *common.ServerTLS, pipeline *Pipeline, authenticator *Authenticator, admission *Admission, handshakeGuard *HandshakeGuard, ticketKeys *TicketKeys, sessions *SessionRegistry, configPusher *ConfigPusher, statuses *StatusRegistry, lifecycle fx.Lifecycle) (*QuicServer, error) {
 	quicConfig := &quic.Config{
-		MaxIdleTimeout:                 time.Duration(config.QuicMaxIdleTimeout) * time.Second,
-		KeepAlivePeriod:                time.Duration(config.QuicKeepAlivePeriod) * time.Second,
-		MaxIncomingStreams:             config.QuicServerMaxIncomingStreams,
-		MaxIncomingUniStreams:          config.QuicServerMaxIncomingUniStreams,
-		InitialStreamReceiveWindow:     config.QuicServerInitialStreamReceiveWindow * 1024 * 1024,     // 스트림 당 초기 버퍼(n MB)
-		MaxStreamReceiveWindow:         config.QuicServerMaxStreamReceiveWindow * 1024 * 1024,         // 스트림 당 최대 버퍼(n MB)
-		InitialConnectionReceiveWindow: config.QuicServerInitialConnectionReceiveWindow * 1024 * 1024, // 연결 당 초기 버퍼(n MB)
-		MaxConnectionReceiveWindow:     config.QuicServerMaxConnectionReceiveWindow * 1024 * 1024,     // 연결 당 최대 버퍼(n MB)
+		MaxIdleTimeout:                 config.Server.MaxIdleTimeout,
+		KeepAlivePeriod:                config.Server.KeepAlivePeriod,
+		MaxIncomingStreams:             config.Server.MaxIncomingStreams,
+		MaxIncomingUniStreams:          config.Server.MaxIncomingUniStreams,
+		InitialStreamReceiveWindow:     config.Server.InitialStreamReceiveWindow.Uint64(),
+		MaxStreamReceiveWindow:         config.Server.MaxStreamReceiveWindow.Uint64(),
+		InitialConnectionReceiveWindow: config.Server.InitialConnectionReceiveWindow.Uint64(),
+		MaxConnectionReceiveWindow:     config.Server.MaxConnectionReceiveWindow.Uint64(),
+		HandshakeIdleTimeout:           config.Server.Handshake.IdleTimeout,
+		Allow0RTT:                      !config.Server.Resumption.Disabled && !config.Server.Resumption.Disable0RTT,
 	}
 
-	listener, err := quic.ListenAddr(config.QuicServerListeningAddress, tls.Config, quicConfig)
+	udpAddr, err := net.ResolveUDPAddr("udp", config.Server.ListeningAddress)
 	if err != nil {
+		return nil, errors.New("Invalid QUIC listening address: " + err.Error())
+	}
+
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, errors.New("Failed to start QUIC server: " + err.Error())
+	}
+
+	transport := &quic.Transport{
+		Conn:                udpConn,
+		MaxTokenAge:         config.Server.Handshake.MaxTokenAge,
+		VerifySourceAddress: handshakeGuard.VerifySourceAddress,
+		ConnContext:         handshakeGuard.ConnContext,
+	}
+
+	// Connections are accepted before their handshake completes, so frames sent in 0-RTT data can be
+	// read early. Only authentication, which changes no state, is acted on before the handshake
+	// completes.
+	listener, err := transport.ListenEarly(tls.Config, quicConfig)
+	if err != nil {
+		transport.Close()
 		return nil, errors.New("Failed to start QUIC server: " + err.Error())
 	}
 
 	server := &QuicServer{
-		listener:   listener,
-		logger:     logger,
-		BufferSize: config.QuicServerStreamBufferSize,
+		transport:      transport,
+		listener:       listener,
+		logger:         logger,
+		pipeline:       pipeline,
+		connections:    newConnectionRegistry(),
+		ingestion:      newIngestionGate(),
+		mutualTLS:      tls.MutualTLS(),
+		authenticator:  authenticator,
+		admission:      admission,
+		sessions:       sessions,
+		configPusher:   configPusher,
+		statuses:       statuses,
+		clientIDSource: config.Server.TLS.ClientIDFrom,
+		tokenRequired:  config.Server.Auth.TokenRequired,
+		authTimeout:    config.Server.Auth.Timeout,
+		maxFrameSize:   config.Server.MaxFrameSize.Int(),
+		drainTimeout:   config.Server.Drain.Timeout,
+		drainRedirect:  config.Server.Drain.RedirectAddress,
+		BufferSize:     config.Server.StreamBufferSize.Int(),
 	}
 
 	var cancel context.CancelFunc
 
 	lifecycle.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
-			logger.Info("Starting QUIC server", zap.String("address", config.QuicServerListeningAddress))
+			logger.Info("Starting QUIC server", zap.String("address", config.Server.ListeningAddress))
 
 			quicCtx, c := context.WithCancel(context.Background())
 			cancel = c
+			server.ctx = quicCtx
 
-			go server.acceptConnections(quicCtx)
+			server.handlers.Go(func() {
+				server.acceptConnections(quicCtx)
+			})
 
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			logger.Info("Stopping QUIC server")
 
+			// The connections still have to drain and their handlers to finish, so a failed close
+			// does not end the shutdown
+			if err := listener.Close(); err != nil {
+				logger.Error("Failed to close QUIC listener", zap.Error(err))
+			}
+
+			// Clients finish their in-flight batches and reconnect elsewhere before the transport closes
+			drainCtx, cancelDrain := context.WithTimeout(ctx, server.drainTimeout)
+			drained := server.Drain(drainCtx, server.drainRedirect, "server shutting down")
+			cancelDrain()
+
+			logger.Info("Connections drained", zap.Int("connections", drained))
+
+			// Closing the transport ends the remaining connections. Their handlers still hand the
+			// frames already read to the pipeline, which is stopped after them.
+			err := transport.Close()
+			if waitErr := server.waitHandlers(ctx); waitErr != nil {
+				err = errors.Join(err, waitErr)
+			}
+
 			if cancel != nil {
 				cancel()
 			}
 
-			return listener.Close()
+			return err
 		},
 	})
 
@@ -90,41 +174,203 @@ func (qs *QuicServer) acceptConnections(ctx context.Context) {
 			continue
 		}
 
+		if qs.draining.Load() {
+			conn.CloseWithError(common.ErrorCodeDraining, "server draining")
+
+			continue
+		}
+
+		release, reason, ok := qs.admission.Admit(conn.RemoteAddr())
+		if !ok {
+			qs.logger.Warn("Rejecting connection", zap.String("remote", conn.RemoteAddr().String()), zap.String("reason", reason))
+			conn.CloseWithError(common.ErrorCodeServerBusy, reason)
+
+			continue
+		}
+
 		qs.logger.Debug("New QUIC connection accepted", zap.String("remote", conn.RemoteAddr().String()))
 
-		go qs.handleConnection(conn)
+		qs.handlers.Go(func() {
+			defer release()
+
+			qs.handleConnection(conn)
+		})
 	}
 }
 
 func (qs *QuicServer) handleConnection(conn *quic.Conn) {
 	defer func() {
-		if err := conn.CloseWithError(0, "connection closed"); err != nil {
+		if err := conn.CloseWithError(common.ErrorCodeNoError, "connection closed"); err != nil {
 			qs.logger.Debug("Failed to close connection", zap.Error(err))
 		}
 	}()
 
-	qs.logger.Debug("Handling new connection", zap.String("remote", conn.RemoteAddr().String()))
+	clientID, err := qs.identify(conn)
+	if err != nil {
+		qs.logger.Warn("Rejecting unidentified connection", zap.String("remote", conn.RemoteAddr().String()), zap.Error(err))
+		conn.CloseWithError(common.ErrorCodeAuthFailed, "client certificate rejected")
+		return
+	}
+
+	connection := newConnection(conn, clientID)
+	ctx := withConnection(conn.Context(), connection)
+
+	if qs.tokenRequired && connection.ClientID == "" {
+		stream, reader, err := qs.authenticate(ctx, conn, connection)
+		if err != nil {
+			qs.logger.Warn("Authentication failed", append(connection.LogFields(), zap.Error(err))...)
+			return
+		}
+
+		qs.handlers.Go(func() {
+			qs.handleStream(ctx, stream, reader)
+		})
+	}
+
+	qs.connections.add(connection)
+	defer qs.connections.remove(connection)
+
+	qs.handlers.Go(func() {
+		qs.sessions.Track(connection)
+	})
+	qs.handlers.Go(func() {
+		qs.configPusher.Push(connection)
+	})
+	qs.handlers.Go(func() {
+		qs.acceptStatusStreams(ctx, connection)
+	})
+
+	logger := qs.logger.With(connection.LogFields()...)
+
+	logger.Debug("Handling new connection")
 
 	for {
-		stream, err := conn.AcceptStream(conn.Context())
+		stream, err := conn.AcceptStream(ctx)
 		if err != nil {
-			qs.logger.Error("Failed to accept stream", zap.Error(err))
+			if code, ok := common.ErrorCodeFromError(err); ok {
+				logger.Debug("Connection closed by client", zap.String("error_code", common.ErrorCodeName(code)), zap.Error(err))
+				return
+			}
+
+			logger.Error("Failed to accept stream", zap.Error(err))
 			return
 		}
 
-		qs.logger.Debug("New stream accepted", zap.Uint64("stream_id", uint64(stream.StreamID())))
+		logger.Debug("New stream accepted", zap.Uint64("stream_id", uint64(stream.StreamID())))
+
+		qs.handlers.Go(func() {
+			qs.handleStream(ctx, stream, qs.newFrameReader(stream))
+		})
+	}
+}
+
+// authenticate expects an AuthRequest as the first frame of the connection and closes it
+// with an application error code when the credential is invalid or does not arrive before the deadline
+func (qs *QuicServer) authenticate(ctx context.Context, conn *quic.Conn, connection *Connection) (*quic.Stream, *common.FrameReader, error) {
+	authCtx, cancel := context.WithTimeout(ctx, qs.authTimeout)
+	defer cancel()
+
+	stream, err := conn.AcceptStream(authCtx)
+	if err != nil {
+		conn.CloseWithError(common.ErrorCodeAuthTimeout, "authentication timeout")
+		return nil, nil, err
+	}
+
+	deadline, _ := authCtx.Deadline()
+	stream.SetReadDeadline(deadline)
+
+	reader := qs.newFrameReader(stream)
+	request := &pb.AuthRequest{}
+	if err := common.ReadMessage(reader, common.FrameAuthRequest, request); err != nil {
+		switch {
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			conn.CloseWithError(common.ErrorCodeAuthTimeout, "authentication timeout")
+		case errors.Is(err, common.ErrFrameTooLarge):
+			conn.CloseWithError(common.ErrorCodeFrameTooLarge, "authentication request too large")
+		default:
+			conn.CloseWithError(common.ErrorCodeProtocolViolation, "authentication request expected")
+		}
+		return nil, nil, err
+	}
+
+	clientID, err := qs.authenticator.Authenticate(authCtx, request)
+	if err != nil && !errors.Is(err, ErrInvalidCredential) {
+		// The credential store failed, so the client may retry
+		conn.CloseWithError(common.ErrorCodeInternal, "authentication unavailable")
+		return nil, nil, err
+	}
+	if err != nil {
+		common.WriteMessage(stream, common.FrameAuthResponse, &pb.AuthResponse{
+			Status:  pb.AuthStatus_AUTH_STATUS_DENIED,
+			Message: "invalid credential",
+		})
+		conn.CloseWithError(common.ErrorCodeAuthFailed, "authentication failed")
+		return nil, nil, err
+	}
+
+	stream.SetReadDeadline(time.Time{})
+	connection.ClientID = clientID
+
+	if err := common.WriteMessage(stream, common.FrameAuthResponse, &pb.AuthResponse{
+		Status:   pb.AuthStatus_AUTH_STATUS_OK,
+		ClientId: clientID,
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	qs.logger.Info("Client authenticated", connection.LogFields()...)
+
+	return stream, reader, nil
+}
+
+func (qs *QuicServer) newFrameReader(stream *quic.Stream) *common.FrameReader {
+	return common.NewFrameReader(stream, qs.BufferSize, qs.maxFrameSize)
+}
+
+// identify returns the client ID carried by the verified client certificate when mutual TLS is enabled
+func (qs *QuicServer) identify(conn *quic.Conn) (string, error) {
+	if !qs.mutualTLS {
+		return "", nil
+	}
+
+	// The certificate is only verified once the handshake completes
+	select {
+	case <-conn.HandshakeComplete():
+	case <-conn.Context().Done():
+		return "", context.Cause(conn.Context())
+	}
 
-		go qs.handleStream(stream)
+	certificates := conn.ConnectionState().TLS.PeerCertificates
+	if len(certificates) == 0 {
+		return "", errors.New("client certificate is missing")
 	}
+
+	return clientIDFromCertificate(certificates[0], qs.clientIDSource)
 }
 
-func (qs *QuicServer) handleStream(stream *quic.Stream) {
-	defer stream.Close()
+// handleStream reads data frames and hands them to the pipeline, which decodes them and writes the acks
+func (qs *QuicServer) handleStream(ctx context.Context, stream *quic.Stream, reader *common.FrameReader) {
+	connection, _ := ConnectionFromContext(ctx)
+
+	writer := newAckWriter(stream)
+	connection.attach(writer)
+	defer func() {
+		// The last acks are written before the writer leaves the connection, and it leaves before
+		// its stream is closed, so control frames no longer pick it up
+		writer.pending.Wait()
+		connection.detach(writer)
+		writer.Close()
+	}()
 
-	buffer := make([]byte, qs.BufferSize)
+	connection.Stats.Streams.Add(1)
+	defer connection.Stats.Streams.Add(-1)
 
 	for {
-		n, err := stream.Read(buffer)
+		if err := qs.ingestion.Wait(ctx); err != nil {
+			return
+		}
+
+		frame, err := reader.ReadFrame()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				qs.logger.Debug("Stream closed by peer", zap.Uint64("stream_id", uint64(stream.StreamID())))
@@ -132,78 +378,188 @@ func (qs *QuicServer) handleStream(stream *quic.Stream) {
 				return
 			}
 
+			if code, ok := common.ErrorCodeFromError(err); ok {
+				qs.logger.Debug("Stream reset by peer",
+					zap.Uint64("stream_id", uint64(stream.StreamID())),
+					zap.String("error_code", common.ErrorCodeName(code)))
+
+				return
+			}
+
+			if errors.Is(err, common.ErrFrameTooLarge) {
+				// The rest of the stream cannot be framed, so the client has to send on a new one
+				qs.logger.Warn("Resetting stream", append(connection.LogFields(),
+					zap.Uint64("stream_id", uint64(stream.StreamID())),
+					zap.Error(err))...)
+				writer.Abort(common.ErrorCodeFrameTooLarge)
+
+				return
+			}
+
 			qs.logger.Error("Failed to read from stream", zap.Error(err))
 
 			return
 		}
 
-		compressedData := buffer[:n]
-
-		// TODO: Snappy Decompression Testing Requirements
-		// 1. Decompression Performance Testing:
-		//    - Measure decompression time for different data sizes
-		//    - Test with various compression ratios
-		//    - Monitor memory usage during decompression
-		//    - Test CPU usage patterns during decompression
-		//
-		// 2. Error Handling Testing:
-		//    - Test with corrupted compressed data
-		//    - Test with incomplete compressed data
-		//    - Test memory exhaustion during decompression
-		//    - Test with malformed protobuf data after decompression
-		//
-		// 3. Concurrent Processing Testing:
-		//    - Test multiple concurrent decompression operations
-		//    - Test memory usage under high load
-		//    - Test performance degradation with multiple streams
-		//
-		// 4. Monitoring and Metrics:
-		//    - Track decompression success/failure rates
-		//    - Monitor average decompression time
-		//    - Track compression ratio statistics
-		//    - Monitor memory usage patterns
-		//
-		// 5. Optimization Opportunities:
-		//    - Implement decompression buffer pooling
-		//    - Test different buffer sizes for optimal performance
-		//    - Consider async decompression for large data
-		//    - Implement decompression timeout handling
-
-		// Decompress data with snappy
-		decompressedData, err := snappy.Decode(nil, compressedData)
-		if err != nil {
-			qs.logger.Error("Failed to decompress snappy data", zap.Error(err))
+		if frame.Type == common.FrameAuthRequest {
+			frame.Release()
+			qs.answerAuthRequest(connection, writer)
 			continue
 		}
 
-		// Unmarshal protobuf message
-		var clientData pb.ClientData
-		if err := proto.Unmarshal(decompressedData, &clientData); err != nil {
-			qs.logger.Error("Failed to unmarshal protobuf message", zap.Error(err))
+		if frame.Type != common.FrameClientData {
+			qs.logger.Warn("Ignoring unexpected frame", zap.Stringer("frame_type", frame.Type))
+			frame.Release()
 			continue
 		}
 
-		// Calculate compression statistics
-		compressionRatio := float64(len(compressedData)) / float64(len(decompressedData)) * 100
-		sizeReduction := len(decompressedData) - len(compressedData)
-
-		qs.logger.Info("Received snappy compressed protobuf message",
-			zap.Int64("timestamp", clientData.Timestamp),
-			zap.Int("message_length", len(clientData.Message)),
-			zap.Int("sensor_readings_count", len(clientData.SensorReadings)),
-			zap.Int("original_size", len(decompressedData)),
-			zap.Int("compressed_size", len(compressedData)),
-			zap.Float64("compression_ratio_percent", compressionRatio),
-			zap.Int("size_reduction_bytes", sizeReduction),
-			zap.Uint64("stream_id", uint64(stream.StreamID())))
-
-		// Send response back to client
-		response := fmt.Sprintf("Server received compressed protobuf (original: %d bytes, compressed: %d bytes, ratio: %.1f%%)",
-			len(decompressedData), len(compressedData), compressionRatio)
-		_, err = stream.Write([]byte(response))
+		// Data frames may come in 0-RTT data, which an attacker can replay, so none is acted on before
+		// the handshake completed. A replayed handshake never completes and its frames are dropped
+		// with the connection.
+		if err := connection.WaitHandshake(ctx); err != nil {
+			frame.Release()
+			return
+		}
+
+		connection.Stats.Observe(len(frame.Payload))
+
+		// A frame read before the connection closed is still processed, so the submission only gives
+		// up once shutdown stops waiting
+		if err := qs.pipeline.Submit(qs.ctx, &frameJob{
+			ctx:        ctx,
+			connection: connection,
+			stream:     writer,
+			frame:      frame,
+			receivedAt: time.Now(),
+		}); err != nil {
+			frame.Release()
+			qs.logger.Debug("Stopped reading stream", zap.Uint64("stream_id", uint64(stream.StreamID())), zap.Error(err))
+			writer.Abort(common.ErrorCodeDraining)
+
+			return
+		}
+	}
+}
+
+// answerAuthRequest answers a credential sent on a stream the server did not authenticate, as tokens
+// are not required or the client was identified already. The client gets the identity the connection
+// has, so it does not wait for a response that never comes.
+func (qs *QuicServer) answerAuthRequest(connection *Connection, writer *ackWriter) {
+	response := &pb.AuthResponse{
+		Status:   pb.AuthStatus_AUTH_STATUS_OK,
+		ClientId: connection.ClientID,
+		Message:  "authentication not required",
+	}
+	if connection.ClientID != "" {
+		response.Message = "client already identified"
+	}
+
+	if err := writer.WriteMessage(common.FrameAuthResponse, response); err != nil {
+		qs.logger.Debug("Failed to answer authentication request", append(connection.LogFields(), zap.Error(err))...)
+	}
+}
+
+// acceptStatusStreams reads the status reports a client sends on the unidirectional streams it opens
+// until the connection closes
+func (qs *QuicServer) acceptStatusStreams(ctx context.Context, connection *Connection) {
+	for {
+		stream, err := connection.conn.AcceptUniStream(ctx)
 		if err != nil {
-			qs.logger.Error("Failed to write to stream", zap.Error(err))
 			return
 		}
+
+		qs.handlers.Go(func() {
+			qs.handleStatusStream(ctx, connection, stream)
+		})
+	}
+}
+
+// handleStatusStream stores every status report read from a status stream. Reports sent in 0-RTT
+// data could be replays, so they wait for the handshake.
+func (qs *QuicServer) handleStatusStream(ctx context.Context, connection *Connection, stream *quic.ReceiveStream) {
+	reader := common.NewFrameReader(stream, common.FrameHeaderSize, common.ControlFrameMaxSize)
+
+	for {
+		report := &pb.ClientStatus{}
+		if err := common.ReadMessage(reader, common.FrameClientStatus, report); err != nil {
+			if _, ok := common.ErrorCodeFromError(err); !ok && !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				qs.logger.Warn("Failed to read status report", append(connection.LogFields(), zap.Error(err))...)
+				stream.CancelRead(quic.StreamErrorCode(common.ErrorCodeProtocolViolation))
+			}
+			return
+		}
+
+		if err := connection.WaitHandshake(ctx); err != nil {
+			return
+		}
+
+		if err := qs.statuses.Record(connection, report); err != nil {
+			qs.logger.Error("Failed to store status report", append(connection.LogFields(), zap.Error(err))...)
+		}
+	}
+}
+
+// waitHandlers waits until the accept loop and every connection and stream handler returned or ctx ends
+func (qs *QuicServer) waitHandlers(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		qs.handlers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		qs.logger.Info("Stream handlers finished")
+		return nil
+	case <-ctx.Done():
+		return errors.New("Stream handlers did not finish: " + ctx.Err().Error())
 	}
 }
+
+// Drain stops admitting connections and drains every live one, telling the clients to reconnect,
+// to endpoint when set. It returns the number of drained connections once all of them are closed,
+// which happens when ctx ends at the latest.
+func (qs *QuicServer) Drain(ctx context.Context, endpoint, reason string) int {
+	qs.draining.Store(true)
+
+	connections := qs.connections.List()
+	drain := &pb.Drain{Endpoint: endpoint, Reason: reason}
+
+	qs.logger.Info("Draining connections",
+		zap.Int("connections", len(connections)),
+		zap.String("redirect", endpoint),
+		zap.String("reason", reason))
+
+	var wg sync.WaitGroup
+	for _, connection := range connections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			connection.Drain(ctx, drain)
+		}()
+	}
+	wg.Wait()
+
+	return len(connections)
+}
+
+// Draining reports whether the server drains its connections and refuses new ones
+func (qs *QuicServer) Draining() bool {
+	return qs.draining.Load()
+}
+
+// Connections returns the registry of live connections
+func (qs *QuicServer) Connections() *ConnectionRegistry {
+	return qs.connections
+}
+
+// Ingestion returns the gate pausing and resuming the stream readers
+func (qs *QuicServer) Ingestion() *IngestionGate {
+	return qs.ingestion
+}
+
+// SinkStatus returns the write status of every sink
+func (qs *QuicServer) SinkStatus() []SinkStatusView {
+	return qs.pipeline.SinkStatus()
+}
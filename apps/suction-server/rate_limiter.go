@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreRedis  = "redis"
+
+	rateLimitIdleExpiry = 24 * time.Hour
+	quotaDayLayout      = "20060102"
+)
+
+// RateDecision is the outcome of a rate limit check
+type RateDecision int
+
+const (
+	RateAllowed RateDecision = iota
+	RateThrottled
+	RateQuotaExceeded
+)
+
+func (d RateDecision) String() string {
+	switch d {
+	case RateAllowed:
+		return "allowed"
+	case RateThrottled:
+		return "throttled"
+	case RateQuotaExceeded:
+		return "quota_exceeded"
+	default:
+		return "unknown"
+	}
+}
+
+// AckStatus returns the ack status reported to the client for the decision
+func (d RateDecision) AckStatus() pb.AckStatus {
+	switch d {
+	case RateThrottled:
+		return pb.AckStatus_ACK_STATUS_THROTTLED
+	case RateQuotaExceeded:
+		return pb.AckStatus_ACK_STATUS_QUOTA_EXCEEDED
+	default:
+		return pb.AckStatus_ACK_STATUS_OK
+	}
+}
+
+// RateLimitStore keeps token bucket and daily quota state per client
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit common.RateLimit, bytes int, now time.Time) (RateDecision, error)
+}
+
+// RateLimiter applies per-client message and byte rate limits and daily quotas to data frames.
+// Limits are resolved from the client entry, its tier and the defaults, and follow config reloads.
+type RateLimiter struct {
+	mu          sync.RWMutex
+	config      common.RateLimitConfig
+	minBurst    common.ByteSize
+	store       RateLimitStore
+	logger      *zap.Logger
+	limited     *prometheus.CounterVec
+	disconnects prometheus.Counter
+}
+
+func NewRateLimiter(logger *zap.Logger, config *common.Config, redisClient *RedisClient, metrics *common.Metrics) (*RateLimiter, error) {
+	var store RateLimitStore
+	switch config.Server.RateLimit.Store {
+	case RateLimitStoreRedis:
+		store = newRedisRateLimitStore(redisClient, config.Redis.RateLimitKeyPrefix)
+	default:
+		store = newMemoryRateLimitStore()
+	}
+
+	limiter := &RateLimiter{
+		config:   config.Server.RateLimit,
+		minBurst: config.Server.MaxFrameSize,
+		store:    store,
+		logger:   logger,
+		limited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "rate_limited_messages_total",
+			Help:      "Data frames rejected by the rate limiter by reason.",
+		}, []string{"reason"}),
+		disconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "rate_limit_disconnects_total",
+			Help:      "Connections closed after repeated rate limit violations.",
+		}),
+	}
+
+	if err := metrics.Registry.Register(limiter.limited); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(limiter.disconnects); err != nil {
+		return nil, err
+	}
+
+	return limiter, nil
+}
+
+// Reload applies changed limits. Existing buckets pick them up on their next check.
+func (rl *RateLimiter) Reload(event common.ReloadEvent) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.config = event.Current.Server.RateLimit
+}
+
+// Allow checks a data frame of the given size against the limits of the connection's client.
+// Store failures are logged and the frame is let through.
+func (rl *RateLimiter) Allow(ctx context.Context, connection *Connection, bytes int) RateDecision {
+	rl.mu.RLock()
+	enabled := rl.config.Enabled
+	limit := rl.limitFor(connection.ClientID)
+	rl.mu.RUnlock()
+
+	if !enabled {
+		return RateAllowed
+	}
+
+	decision, err := rl.store.Take(ctx, rateLimitKey(connection), limit, bytes, time.Now())
+	if err != nil {
+		rl.logger.Error("Rate limit check failed", append(connection.LogFields(), zap.Error(err))...)
+		return RateAllowed
+	}
+
+	if decision != RateAllowed {
+		rl.limited.WithLabelValues(decision.String()).Inc()
+	}
+
+	return decision
+}
+
+// RetryAfter returns how long the client of a connection should wait before sending a frame of the
+// given size again after the decision: until its buckets refilled enough for the frame, or until the
+// daily quota resets at midnight UTC
+func (rl *RateLimiter) RetryAfter(connection *Connection, decision RateDecision, bytes int, now time.Time) time.Duration {
+	switch decision {
+	case RateQuotaExceeded:
+		return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+	case RateThrottled:
+		rl.mu.RLock()
+		limit := rl.limitFor(connection.ClientID)
+		rl.mu.RUnlock()
+
+		var wait time.Duration
+		if limit.MessagesPerSecond > 0 {
+			wait = time.Duration(float64(time.Second) / limit.MessagesPerSecond)
+		}
+		if limit.BytesPerSecond > 0 {
+			wait = max(wait, time.Duration(float64(bytes)/float64(limit.BytesPerSecond)*float64(time.Second)))
+		}
+
+		return wait
+	default:
+		return 0
+	}
+}
+
+// RecordViolation counts a rejected frame and reports whether the connection exceeded
+// the violations allowed within the window and should be closed
+func (rl *RateLimiter) RecordViolation(connection *Connection) bool {
+	rl.mu.RLock()
+	maxViolations := rl.config.MaxViolations
+	window := rl.config.ViolationWindow
+	rl.mu.RUnlock()
+
+	if maxViolations == 0 {
+		return false
+	}
+
+	if connection.violations.add(time.Now(), window) <= maxViolations {
+		return false
+	}
+
+	rl.disconnects.Inc()
+
+	return true
+}
+
+// limitFor resolves the limits of a client. Callers must hold rl.mu.
+func (rl *RateLimiter) limitFor(clientID string) common.RateLimit {
+	limit := rl.config.Default
+
+	client, ok := rl.config.Clients[clientID]
+	if !ok {
+		return rl.withBursts(limit)
+	}
+
+	if client.Tier != "" {
+		limit = rl.config.Tiers[client.Tier]
+	}
+	if client.MessagesPerSecond > 0 {
+		limit.MessagesPerSecond = client.MessagesPerSecond
+	}
+	if client.MessagesBurst > 0 {
+		limit.MessagesBurst = client.MessagesBurst
+	}
+	if client.BytesPerSecond > 0 {
+		limit.BytesPerSecond = client.BytesPerSecond
+	}
+	if client.BytesBurst > 0 {
+		limit.BytesBurst = client.BytesBurst
+	}
+	if client.DailyQuota > 0 {
+		limit.DailyQuota = client.DailyQuota
+	}
+
+	return rl.withBursts(limit)
+}
+
+// withBursts fills in unset bursts: one second worth of messages, and one second worth of bytes
+// but never less than the maximum frame size so a single large frame can pass
+func (rl *RateLimiter) withBursts(limit common.RateLimit) common.RateLimit {
+	if limit.MessagesBurst == 0 {
+		limit.MessagesBurst = burstOf(limit.MessagesPerSecond)
+	}
+	if limit.BytesBurst == 0 {
+		limit.BytesBurst = max(limit.BytesPerSecond, rl.minBurst)
+	}
+
+	return limit
+}
+
+// rateLimitKey identifies the client of a connection, falling back to the remote IP for anonymous connections
+func rateLimitKey(connection *Connection) string {
+	if connection.ClientID != "" {
+		return "client:" + connection.ClientID
+	}
+
+	host, _, err := net.SplitHostPort(connection.RemoteAddr)
+	if err != nil {
+		host = connection.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// violationCounter counts rate limit violations within a window starting at the first violation
+type violationCounter struct {
+	mu    sync.Mutex
+	since time.Time
+	count int
+}
+
+func (v *violationCounter) add(now time.Time, window time.Duration) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.since) > window {
+		v.since = now
+		v.count = 0
+	}
+	v.count++
+
+	return v.count
+}
+
+// memoryRateLimitStore keeps buckets in process memory, limiting each server instance independently
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	messages *rate.Limiter
+	bytes    *rate.Limiter
+	day      string
+	used     uint64
+	lastSeen time.Time
+}
+
+func newMemoryRateLimitStore() *memoryRateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, limit common.RateLimit, bytes int, now time.Time) (RateDecision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{
+			messages: rate.NewLimiter(rateOf(limit.MessagesPerSecond), limit.MessagesBurst),
+			bytes:    rate.NewLimiter(rateOf(float64(limit.BytesPerSecond)), limit.BytesBurst.Int()),
+		}
+		s.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+
+	updateLimiter(bucket.messages, now, rateOf(limit.MessagesPerSecond), limit.MessagesBurst)
+	updateLimiter(bucket.bytes, now, rateOf(float64(limit.BytesPerSecond)), limit.BytesBurst.Int())
+
+	day := now.UTC().Format(quotaDayLayout)
+	if bucket.day != day {
+		bucket.day = day
+		bucket.used = 0
+	}
+
+	if limit.DailyQuota > 0 && bucket.used+uint64(bytes) > limit.DailyQuota.Uint64() {
+		return RateQuotaExceeded, nil
+	}
+
+	messages := bucket.messages.ReserveN(now, 1)
+	if !messages.OK() || messages.DelayFrom(now) > 0 {
+		messages.CancelAt(now)
+		return RateThrottled, nil
+	}
+
+	payload := bucket.bytes.ReserveN(now, bytes)
+	if !payload.OK() || payload.DelayFrom(now) > 0 {
+		payload.CancelAt(now)
+		messages.CancelAt(now)
+		return RateThrottled, nil
+	}
+
+	bucket.used += uint64(bytes)
+
+	return RateAllowed, nil
+}
+
+// sweep drops buckets of clients that have been idle for a day, at most once per hour
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Hour {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.lastSeen) > rateLimitIdleExpiry {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func updateLimiter(limiter *rate.Limiter, now time.Time, limit rate.Limit, burst int) {
+	if limiter.Limit() != limit {
+		limiter.SetLimitAt(now, limit)
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurstAt(now, burst)
+	}
+}
+
+func rateOf(perSecond float64) rate.Limit {
+	if perSecond <= 0 {
+		return rate.Inf
+	}
+
+	return rate.Limit(perSecond)
+}
+
+// burstOf allows one second worth of events at once
+func burstOf(perSecond float64) int {
+	return max(1, int(math.Ceil(perSecond)))
+}
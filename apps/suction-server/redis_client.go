@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go/common"
@@ -17,7 +18,7 @@ type RedisClient struct {
 }
 
 func NewRedisClient(logger *zap.Logger, config *common.Config, lifecycle fx.Lifecycle) (*RedisClient, error) {
-	opts, err := redis.ParseURL(config.RedisURL)
+	opts, err := redis.ParseURL(config.Redis.URL)
 	if err != nil {
 		return nil, err
 	}
@@ -56,3 +57,65 @@ func NewRedisClient(logger *zap.Logger, config *common.Config, lifecycle fx.Life
 
 	return redisClient, nil
 }
+
+// AddToStream appends an entry to a stream trimmed to approximately maxLen entries
+func (rc *RedisClient) AddToStream(ctx context.Context, stream string, maxLen int64, values map[string]any) error {
+	return rc.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+// Get returns the value of a key and whether it exists
+func (rc *RedisClient) Get(ctx context.Context, key string) (string, bool, error) {
+	value, err := rc.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// HashGet returns a hash field and whether it exists
+func (rc *RedisClient) HashGet(ctx context.Context, key, field string) (string, bool, error) {
+	value, err := rc.client.HGet(ctx, key, field).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	return value, true, nil
+}
+
+// HashGetAll scans the fields of a hash into dest, a struct with redis field tags
+func (rc *RedisClient) HashGetAll(ctx context.Context, key string, dest any) error {
+	return rc.client.HGetAll(ctx, key).Scan(dest)
+}
+
+// SortedSetRangeByScore returns the members scored between min and max, in Redis range syntax
+func (rc *RedisClient) SortedSetRangeByScore(ctx context.Context, key, min, max string) ([]string, error) {
+	return rc.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max}).Result()
+}
+
+// RunScript runs a Lua script, loading it into the script cache when missing
+func (rc *RedisClient) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...any) (any, error) {
+	return script.Run(ctx, rc.client, keys, args...).Result()
+}
+
+// TxPipelined runs the commands queued by fn in a MULTI/EXEC transaction
+func (rc *RedisClient) TxPipelined(ctx context.Context, fn func(pipe redis.Pipeliner) error) error {
+	_, err := rc.client.TxPipelined(ctx, fn)
+	return err
+}
+
+// Publish sends a message to a pub/sub channel
+func (rc *RedisClient) Publish(ctx context.Context, channel string, message any) error {
+	return rc.client.Publish(ctx, channel, message).Err()
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go/common"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const quotaKeyTTL = 48 * time.Hour
+
+// takeScript atomically checks the daily quota and both token buckets, and only consumes
+// tokens and quota when every check passes.
+//
+// KEYS: message bucket, byte bucket, daily quota counter
+// ARGV: now (ms), message rate, message burst, byte rate, byte burst, bytes, quota, quota ttl (s)
+// Returns 0 when allowed, 1 when throttled and 2 when the quota is exceeded.
+var takeScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local bytes = tonumber(ARGV[6])
+local quota = tonumber(ARGV[7])
+
+if quota > 0 then
+	local used = tonumber(redis.call('GET', KEYS[3]) or '0')
+	if used + bytes > quota then
+		return 2
+	end
+end
+
+local function refill(key, rate, burst)
+	local state = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(state[1]) or burst
+	local ts = tonumber(state[2]) or now
+	return math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+end
+
+local messageRate, messageBurst = tonumber(ARGV[2]), tonumber(ARGV[3])
+local byteRate, byteBurst = tonumber(ARGV[4]), tonumber(ARGV[5])
+
+local messageTokens, byteTokens
+if messageRate > 0 then
+	messageTokens = refill(KEYS[1], messageRate, messageBurst)
+	if messageTokens < 1 then
+		return 1
+	end
+end
+if byteRate > 0 then
+	byteTokens = refill(KEYS[2], byteRate, byteBurst)
+	if byteTokens < bytes then
+		return 1
+	end
+end
+
+if messageTokens then
+	redis.call('HSET', KEYS[1], 'tokens', tostring(messageTokens - 1), 'ts', tostring(now))
+	redis.call('PEXPIRE', KEYS[1], math.ceil(messageBurst / messageRate * 1000) + 1000)
+end
+if byteTokens then
+	redis.call('HSET', KEYS[2], 'tokens', tostring(byteTokens - bytes), 'ts', tostring(now))
+	redis.call('PEXPIRE', KEYS[2], math.ceil(byteBurst / byteRate * 1000) + 1000)
+end
+if quota > 0 then
+	redis.call('INCRBY', KEYS[3], bytes)
+	redis.call('EXPIRE', KEYS[3], ARGV[8])
+end
+
+return 0
+`)
+
+// redisRateLimitStore keeps buckets in Redis so limits are shared by every server instance
+type redisRateLimitStore struct {
+	redisClient *RedisClient
+	prefix      string
+}
+
+func newRedisRateLimitStore(redisClient *RedisClient, prefix string) *redisRateLimitStore {
+	return &redisRateLimitStore{redisClient: redisClient, prefix: prefix}
+}
+
+func (s *redisRateLimitStore) Take(ctx context.Context, key string, limit common.RateLimit, bytes int, now time.Time) (RateDecision, error) {
+	// The hash tag keeps the keys of a client in one cluster slot
+	base := s.prefix + ":{" + key + "}"
+	keys := []string{
+		base + ":messages",
+		base + ":bytes",
+		base + ":quota:" + now.UTC().Format(quotaDayLayout),
+	}
+
+	result, err := s.redisClient.RunScript(ctx, takeScript, keys,
+		now.UnixMilli(),
+		limit.MessagesPerSecond,
+		limit.MessagesBurst,
+		limit.BytesPerSecond.Uint64(),
+		limit.BytesBurst.Uint64(),
+		bytes,
+		limit.DailyQuota.Uint64(),
+		int64(quotaKeyTTL.Seconds()),
+	)
+	if err != nil {
+		return RateAllowed, err
+	}
+
+	code, ok := result.(int64)
+	if !ok {
+		return RateAllowed, errors.New("unexpected rate limit script result")
+	}
+
+	switch code {
+	case 1:
+		return RateThrottled, nil
+	case 2:
+		return RateQuotaExceeded, nil
+	default:
+		return RateAllowed, nil
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"go/common"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	SessionEventConnected    = "connected"
+	SessionEventDisconnected = "disconnected"
+
+	sessionWriteTimeout = 3 * time.Second
+)
+
+// Session is the registry entry of a live connection. It is stored as a Redis hash under
+// "<prefix>:<connection id>" and indexed in the sorted set "<prefix>" scored by expiry in unix milliseconds.
+// Times are unix milliseconds.
+type Session struct {
+	ConnectionID string `json:"connection_id" redis:"connection_id"`
+	ClientID     string `json:"client_id" redis:"client_id"`
+	RemoteAddr   string `json:"remote_addr" redis:"remote_addr"`
+	Instance     string `json:"instance" redis:"instance"`
+	ConnectedAt  int64  `json:"connected_at" redis:"connected_at"`
+	LastSeenAt   int64  `json:"last_seen_at" redis:"last_seen_at"`
+	Frames       uint64 `json:"frames" redis:"frames"`
+	Bytes        uint64 `json:"bytes" redis:"bytes"`
+	Records      uint64 `json:"records" redis:"records"`
+	Rejected     uint64 `json:"rejected" redis:"rejected"`
+}
+
+// SessionEvent is published as JSON on the session channel when a client connects or disconnects
+type SessionEvent struct {
+	Type string `json:"type"`
+	Session
+}
+
+// SessionRegistry records the connected clients in Redis while their connection lives
+type SessionRegistry struct {
+	redisClient     *RedisClient
+	logger          *zap.Logger
+	instance        string
+	keyPrefix       string
+	channel         string
+	ttl             time.Duration
+	refreshInterval time.Duration
+}
+
+func NewSessionRegistry(logger *zap.Logger, config *common.Config, redisClient *RedisClient) (*SessionRegistry, error) {
+	instance := config.Server.Sessions.InstanceID
+	if instance == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, err
+		}
+		instance = hostname
+	}
+
+	return &SessionRegistry{
+		redisClient:     redisClient,
+		logger:          logger,
+		instance:        instance,
+		keyPrefix:       config.Redis.SessionKeyPrefix,
+		channel:         config.Redis.SessionChannel,
+		ttl:             config.Server.Sessions.TTL,
+		refreshInterval: config.Server.Sessions.RefreshInterval,
+	}, nil
+}
+
+// Instance returns the ID of this server instance
+func (r *SessionRegistry) Instance() string {
+	return r.instance
+}
+
+// Track registers the session of a connection and refreshes it until the connection closes, then removes it
+func (r *SessionRegistry) Track(connection *Connection) {
+	logger := r.logger.With(connection.LogFields()...)
+
+	if err := r.save(connection); err != nil {
+		logger.Error("Failed to register session", zap.Error(err))
+	}
+	r.publish(SessionEventConnected, connection, logger)
+
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-connection.conn.Context().Done():
+			if err := r.remove(connection); err != nil {
+				logger.Error("Failed to remove session", zap.Error(err))
+			}
+			r.publish(SessionEventDisconnected, connection, logger)
+
+			return
+		case <-ticker.C:
+			if err := r.save(connection); err != nil {
+				logger.Error("Failed to refresh session", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (r *SessionRegistry) session(connection *Connection) Session {
+	return Session{
+		ConnectionID: connection.ID,
+		ClientID:     connection.ClientID,
+		RemoteAddr:   connection.RemoteAddr,
+		Instance:     r.instance,
+		ConnectedAt:  connection.ConnectedAt.UnixMilli(),
+		LastSeenAt:   connection.Stats.LastSeenAt.Load(),
+		Frames:       connection.Stats.Frames.Load(),
+		Bytes:        connection.Stats.Bytes.Load(),
+		Records:      connection.Stats.Records.Load(),
+		Rejected:     connection.Stats.Rejected.Load(),
+	}
+}
+
+// save writes the session with a fresh TTL and drops expired entries of crashed instances from the index
+func (r *SessionRegistry) save(connection *Connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionWriteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	key := r.keyPrefix + ":" + connection.ID
+
+	return r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, r.session(connection))
+		pipe.PExpire(ctx, key, r.ttl)
+		pipe.ZAdd(ctx, r.keyPrefix, redis.Z{Score: float64(now.Add(r.ttl).UnixMilli()), Member: connection.ID})
+		pipe.ZRemRangeByScore(ctx, r.keyPrefix, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+
+		return nil
+	})
+}
+
+func (r *SessionRegistry) remove(connection *Connection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), sessionWriteTimeout)
+	defer cancel()
+
+	return r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.keyPrefix+":"+connection.ID)
+		pipe.ZRem(ctx, r.keyPrefix, connection.ID)
+
+		return nil
+	})
+}
+
+func (r *SessionRegistry) publish(eventType string, connection *Connection, logger *zap.Logger) {
+	message, err := json.Marshal(SessionEvent{Type: eventType, Session: r.session(connection)})
+	if err != nil {
+		logger.Error("Failed to encode session event", zap.Error(err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sessionWriteTimeout)
+	defer cancel()
+
+	if err := r.redisClient.Publish(ctx, r.channel, message); err != nil {
+		logger.Error("Failed to publish session event", zap.String("event", eventType), zap.Error(err))
+	}
+}
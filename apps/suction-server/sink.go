@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"go.uber.org/zap"
+)
+
+const SinkRedisStream = "redis"
+
+// Record is a decoded client message handed to sinks together with the identity of its sender
+type Record struct {
+	ConnectionID string
+	ClientID     string
+	RemoteAddr   string
+	StreamID     int64
+	ReceivedAt   time.Time
+	Payload      []byte
+	Data         *pb.ClientData
+}
+
+// Sink stores decoded records. The record and its buffers are reused once Write returns,
+// so sinks must not retain them or buffer writes past it.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, record *Record) error
+}
+
+// NewSinks builds the sinks enabled in the configuration
+func NewSinks(logger *zap.Logger, config *common.Config, redisClient *RedisClient) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(config.Server.Sinks))
+
+	for _, name := range config.Server.Sinks {
+		switch name {
+		case SinkRedisStream:
+			sinks = append(sinks, NewRedisStreamSink(redisClient, config.Redis.RecordStream, config.Redis.RecordStreamMaxLen))
+		default:
+			return nil, errors.New("Unknown sink: " + name)
+		}
+
+		logger.Info("Sink enabled", zap.String("sink", name))
+	}
+
+	return sinks, nil
+}
+
+// SinkStatus tracks the writes of a sink
+type SinkStatus struct {
+	name        string
+	written     atomic.Uint64
+	failed      atomic.Uint64
+	failing     atomic.Bool
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// SinkStatusView is a point-in-time copy of a SinkStatus
+type SinkStatusView struct {
+	Name        string    `json:"name"`
+	Written     uint64    `json:"written"`
+	Failed      uint64    `json:"failed"`
+	Failing     bool      `json:"failing"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at,omitzero"`
+}
+
+func newSinkStatus(name string) *SinkStatus {
+	return &SinkStatus{name: name}
+}
+
+func (s *SinkStatus) observe(err error) {
+	s.failing.Store(err != nil)
+
+	if err == nil {
+		s.written.Add(1)
+		return
+	}
+
+	s.failed.Add(1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+}
+
+// View returns a copy of the status
+func (s *SinkStatus) View() SinkStatusView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SinkStatusView{
+		Name:        s.name,
+		Written:     s.written.Load(),
+		Failed:      s.failed.Load(),
+		Failing:     s.failing.Load(),
+		LastError:   s.lastError,
+		LastErrorAt: s.lastErrorAt,
+	}
+}
+
+// RedisStreamSink appends records to a capped Redis stream
+type RedisStreamSink struct {
+	redisClient *RedisClient
+	stream      string
+	maxLen      int64
+}
+
+func NewRedisStreamSink(redisClient *RedisClient, stream string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{
+		redisClient: redisClient,
+		stream:      stream,
+		maxLen:      maxLen,
+	}
+}
+
+func (s *RedisStreamSink) Name() string {
+	return SinkRedisStream
+}
+
+func (s *RedisStreamSink) Write(ctx context.Context, record *Record) error {
+	return s.redisClient.AddToStream(ctx, s.stream, s.maxLen, map[string]any{
+		"connection_id": record.ConnectionID,
+		"client_id":     record.ClientID,
+		"remote":        record.RemoteAddr,
+		"stream_id":     strconv.FormatInt(record.StreamID, 10),
+		"received_at":   record.ReceivedAt.UnixMilli(),
+		"data":          record.Payload,
+	})
+}
+
+// DeadLetterStream keeps records a sink failed to store in a capped Redis stream for inspection and replay
+type DeadLetterStream struct {
+	redisClient *RedisClient
+	stream      string
+	maxLen      int64
+}
+
+func NewDeadLetterStream(config *common.Config, redisClient *RedisClient) *DeadLetterStream {
+	return &DeadLetterStream{
+		redisClient: redisClient,
+		stream:      config.Redis.DeadLetterStream,
+		maxLen:      config.Redis.DeadLetterMaxLen,
+	}
+}
+
+// Write appends a record together with the sink and the error that rejected it
+func (d *DeadLetterStream) Write(ctx context.Context, record *Record, sink string, cause error) error {
+	return d.redisClient.AddToStream(ctx, d.stream, d.maxLen, map[string]any{
+		"sink":          sink,
+		"error":         cause.Error(),
+		"failed_at":     time.Now().UnixMilli(),
+		"connection_id": record.ConnectionID,
+		"client_id":     record.ClientID,
+		"remote":        record.RemoteAddr,
+		"stream_id":     strconv.FormatInt(record.StreamID, 10),
+		"received_at":   record.ReceivedAt.UnixMilli(),
+		"data":          record.Payload,
+	})
+}
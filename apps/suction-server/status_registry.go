@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	StatusEventStale   = "stale"
+	StatusEventResumed = "resumed"
+
+	statusWriteTimeout = 3 * time.Second
+)
+
+// markStaleScript sets stale_since on the status hashes that exist and are not marked yet, and
+// returns 1 for each hash it marked. A hash that expired is not created again without its TTL.
+//
+// KEYS: client status hashes
+// ARGV: now (ms)
+var markStaleScript = redis.NewScript(`
+local marked = {}
+for i, key in ipairs(KEYS) do
+	marked[i] = 0
+	if redis.call('EXISTS', key) == 1 then
+		marked[i] = redis.call('HSETNX', key, 'stale_since', ARGV[1])
+	end
+end
+return marked
+`)
+
+// ClientStatus is the latest status report of a client. It is stored as a Redis hash under
+// "<prefix>:<client id>" and indexed in the sorted set "<prefix>" scored by report time in unix
+// milliseconds. Clients connected without an identity are stored as "host:<hostname>". Times are
+// unix milliseconds; stale_since is set while the reports of the client are stale.
+type ClientStatus struct {
+	ClientID         string  `json:"client_id" redis:"client_id"`
+	ConnectionID     string  `json:"connection_id" redis:"connection_id"`
+	Instance         string  `json:"instance" redis:"instance"`
+	ReportedAt       int64   `json:"reported_at" redis:"reported_at"`
+	Version          string  `json:"version" redis:"version"`
+	StartedAt        int64   `json:"started_at" redis:"started_at"`
+	UptimeSeconds    uint64  `json:"uptime_seconds" redis:"uptime_seconds"`
+	PoolItems        uint32  `json:"pool_items" redis:"pool_items"`
+	PoolMaxItems     uint32  `json:"pool_max_items" redis:"pool_max_items"`
+	SpoolBytes       uint64  `json:"spool_bytes" redis:"spool_bytes"`
+	DroppedItems     uint64  `json:"dropped_items" redis:"dropped_items"`
+	RejectedBatches  uint64  `json:"rejected_batches" redis:"rejected_batches"`
+	LastError        string  `json:"last_error" redis:"last_error"`
+	LastErrorAt      int64   `json:"last_error_at" redis:"last_error_at"`
+	ConfigVersion    uint64  `json:"config_version" redis:"config_version"`
+	Hostname         string  `json:"hostname" redis:"hostname"`
+	CPUPercent       float64 `json:"cpu_percent" redis:"cpu_percent"`
+	MemoryTotalBytes uint64  `json:"memory_total_bytes" redis:"memory_total_bytes"`
+	MemoryUsedBytes  uint64  `json:"memory_used_bytes" redis:"memory_used_bytes"`
+	DiskPath         string  `json:"disk_path" redis:"disk_path"`
+	DiskTotalBytes   uint64  `json:"disk_total_bytes" redis:"disk_total_bytes"`
+	DiskUsedBytes    uint64  `json:"disk_used_bytes" redis:"disk_used_bytes"`
+	StaleSince       int64   `json:"stale_since,omitempty" redis:"stale_since,omitempty"`
+}
+
+// StatusEvent is published as JSON on the status channel when the reports of a client go stale
+// and when they resume
+type StatusEvent struct {
+	Type string `json:"type"`
+	ClientStatus
+}
+
+// StatusRegistry keeps the latest status report of every client in Redis and alerts once a client
+// stops reporting. Every instance checks for stale clients; the first one to mark a client stale
+// alerts for it.
+type StatusRegistry struct {
+	redisClient   *RedisClient
+	logger        *zap.Logger
+	instance      string
+	keyPrefix     string
+	channel       string
+	staleAfter    time.Duration
+	checkInterval time.Duration
+	ttl           time.Duration
+	done          chan struct{}
+
+	reports prometheus.Counter
+	stale   prometheus.Gauge
+	alerts  prometheus.Counter
+}
+
+func NewStatusRegistry(logger *zap.Logger, config *common.Config, redisClient *RedisClient, sessions *SessionRegistry, metrics *common.Metrics, lifecycle fx.Lifecycle) (*StatusRegistry, error) {
+	registry := &StatusRegistry{
+		redisClient:   redisClient,
+		logger:        logger,
+		instance:      sessions.Instance(),
+		keyPrefix:     config.Redis.StatusKeyPrefix,
+		channel:       config.Redis.StatusChannel,
+		staleAfter:    config.Server.Status.StaleAfter,
+		checkInterval: config.Server.Status.CheckInterval,
+		ttl:           config.Server.Status.TTL,
+		done:          make(chan struct{}),
+		reports: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "client_status_reports_total",
+			Help:      "Status reports received from clients.",
+		}),
+		stale: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "client_status_stale",
+			Help:      "Clients whose last status report is older than the stale threshold, as of the last check.",
+		}),
+		alerts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "suction",
+			Subsystem: "server",
+			Name:      "client_status_stale_alerts_total",
+			Help:      "Clients this instance reported as stale.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{registry.reports, registry.stale, registry.alerts} {
+		if err := metrics.Registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	var cancel context.CancelFunc
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			watchCtx, c := context.WithCancel(context.Background())
+			cancel = c
+
+			go registry.watch(watchCtx)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			cancel()
+			<-registry.done
+
+			return nil
+		},
+	})
+
+	return registry, nil
+}
+
+// Record stores a status report of the client of a connection. A client whose reports were stale
+// is reported as resumed.
+func (r *StatusRegistry) Record(connection *Connection, report *pb.ClientStatus) error {
+	ctx, cancel := context.WithTimeout(context.Background(), statusWriteTimeout)
+	defer cancel()
+
+	status := r.status(connection, report)
+	key := r.keyPrefix + ":" + status.ClientID
+
+	var staleSince *redis.StringCmd
+	err := r.redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		staleSince = pipe.HGet(ctx, key, "stale_since")
+		pipe.HDel(ctx, key, "stale_since")
+		pipe.HSet(ctx, key, status)
+		pipe.PExpire(ctx, key, r.ttl)
+		pipe.ZAdd(ctx, r.keyPrefix, redis.Z{Score: float64(status.ReportedAt), Member: status.ClientID})
+		pipe.ZRemRangeByScore(ctx, r.keyPrefix, "-inf", strconv.FormatInt(time.Now().Add(-r.ttl).UnixMilli(), 10))
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	r.reports.Inc()
+
+	if since, err := staleSince.Int64(); err == nil {
+		r.logger.Info("Client status reports resumed", append(connection.LogFields(),
+			zap.String("status_client_id", status.ClientID),
+			zap.Time("stale_since", time.UnixMilli(since)))...)
+		r.publish(ctx, StatusEventResumed, status)
+	}
+
+	return nil
+}
+
+func (r *StatusRegistry) status(connection *Connection, report *pb.ClientStatus) ClientStatus {
+	host := report.GetHost()
+
+	clientID := connection.ClientID
+	if clientID == "" {
+		clientID = "host:" + host.GetHostname()
+	}
+
+	return ClientStatus{
+		ClientID:         clientID,
+		ConnectionID:     connection.ID,
+		Instance:         r.instance,
+		ReportedAt:       time.Now().UnixMilli(),
+		Version:          report.Version,
+		StartedAt:        report.StartedAt,
+		UptimeSeconds:    report.UptimeSeconds,
+		PoolItems:        report.PoolItems,
+		PoolMaxItems:     report.PoolMaxItems,
+		SpoolBytes:       report.SpoolBytes,
+		DroppedItems:     report.DroppedItems,
+		RejectedBatches:  report.RejectedBatches,
+		LastError:        report.LastError,
+		LastErrorAt:      report.LastErrorAt,
+		ConfigVersion:    report.ConfigVersion,
+		Hostname:         host.GetHostname(),
+		CPUPercent:       host.GetCpuPercent(),
+		MemoryTotalBytes: host.GetMemoryTotalBytes(),
+		MemoryUsedBytes:  host.GetMemoryUsedBytes(),
+		DiskPath:         host.GetDiskPath(),
+		DiskTotalBytes:   host.GetDiskTotalBytes(),
+		DiskUsedBytes:    host.GetDiskUsedBytes(),
+	}
+}
+
+// watch checks for stale clients every check interval until ctx ends
+func (r *StatusRegistry) watch(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.checkStale(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("Failed to check client status reports", zap.Error(err))
+			}
+		}
+	}
+}
+
+// checkStale marks the clients whose last report is older than the stale threshold and alerts for
+// those not marked yet
+func (r *StatusRegistry) checkStale(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, statusWriteTimeout)
+	defer cancel()
+
+	now := time.Now()
+	threshold := strconv.FormatInt(now.Add(-r.staleAfter).UnixMilli(), 10)
+	expired := strconv.FormatInt(now.Add(-r.ttl).UnixMilli(), 10)
+
+	clientIDs, err := r.redisClient.SortedSetRangeByScore(ctx, r.keyPrefix, "("+expired, threshold)
+	if err != nil {
+		return err
+	}
+	r.stale.Set(float64(len(clientIDs)))
+
+	if len(clientIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(clientIDs))
+	for i, clientID := range clientIDs {
+		keys[i] = r.keyPrefix + ":" + clientID
+	}
+
+	result, err := r.redisClient.RunScript(ctx, markStaleScript, keys, now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	marked, ok := result.([]any)
+	if !ok || len(marked) != len(clientIDs) {
+		return errors.New("unexpected stale marks returned")
+	}
+
+	for i, clientID := range clientIDs {
+		if flag, _ := marked[i].(int64); flag != 1 {
+			continue
+		}
+
+		status := ClientStatus{}
+		if err := r.redisClient.HashGetAll(ctx, r.keyPrefix+":"+clientID, &status); err != nil {
+			return err
+		}
+
+		status.StaleSince = now.UnixMilli()
+
+		r.alerts.Inc()
+		r.logger.Warn("Client stopped sending status reports",
+			zap.String("client_id", clientID),
+			zap.String("hostname", status.Hostname),
+			zap.Time("last_report_at", time.UnixMilli(status.ReportedAt)),
+			zap.String("last_error", status.LastError))
+		r.publish(ctx, StatusEventStale, status)
+	}
+
+	return nil
+}
+
+func (r *StatusRegistry) publish(ctx context.Context, eventType string, status ClientStatus) {
+	message, err := json.Marshal(StatusEvent{Type: eventType, ClientStatus: status})
+	if err != nil {
+		r.logger.Error("Failed to encode status event", zap.Error(err))
+		return
+	}
+
+	if err := r.redisClient.Publish(ctx, r.channel, message); err != nil {
+		r.logger.Error("Failed to publish status event", zap.String("event", eventType), zap.Error(err))
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go/common"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const ticketKeyTimeout = 3 * time.Second
+
+// rotateTicketKeysScript adds the candidate key when the newest key is due for rotation, drops keys
+// past their lifetime and returns the remaining keys newest first. Every instance runs it, and the
+// first one to find the newest key expired adds its candidate.
+//
+// KEYS: ticket key sorted set scored by creation time (ms)
+// ARGV: now (ms), rotation (ms), lifetime (ms), candidate key (hex)
+var rotateTicketKeysScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local newest = redis.call('ZREVRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if #newest == 0 or tonumber(newest[2]) <= now - tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', '(' .. (now - tonumber(ARGV[3])))
+return redis.call('ZREVRANGE', KEYS[1], 0, -1)
+`)
+
+// TicketKeys shares TLS session ticket keys between server instances through Redis, so a client
+// resumes its session, and sends 0-RTT data, with whichever instance it reconnects to.
+// Keys are rotated and refreshed in the background; the newest key encrypts new tickets.
+type TicketKeys struct {
+	redisClient *RedisClient
+	tlsConfig   *tls.Config
+	logger      *zap.Logger
+	key         string
+	rotation    time.Duration
+	lifetime    time.Duration
+	refresh     time.Duration
+	newest      string
+}
+
+func NewTicketKeys(logger *zap.Logger, config *common.Config, tls *common.ServerTLS, redisClient *RedisClient, lifecycle fx.Lifecycle) *TicketKeys {
+	resumption := config.Server.Resumption
+	ticketKeys := &TicketKeys{
+		redisClient: redisClient,
+		tlsConfig:   tls.Config,
+		logger:      logger,
+		key:         config.Redis.TicketKeysKey,
+		rotation:    resumption.TicketKeyRotation,
+		lifetime:    resumption.TicketKeyLifetime,
+		refresh:     resumption.TicketKeyRefresh,
+	}
+
+	if resumption.Disabled {
+		return ticketKeys
+	}
+
+	done := make(chan struct{})
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := ticketKeys.update(ctx); err != nil {
+				return errors.New("Failed to load session ticket keys: " + err.Error())
+			}
+
+			go ticketKeys.run(done)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(done)
+
+			return nil
+		},
+	})
+
+	return ticketKeys
+}
+
+// run refreshes the keys until done is closed. Failures keep the current keys, which stay valid
+// until their lifetime ends.
+func (t *TicketKeys) run(done <-chan struct{}) {
+	ticker := time.NewTicker(t.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), ticketKeyTimeout)
+			if err := t.update(ctx); err != nil {
+				t.logger.Error("Failed to refresh session ticket keys", zap.Error(err))
+			}
+			cancel()
+		}
+	}
+}
+
+// update rotates the shared keys when due and installs them on the TLS configuration
+func (t *TicketKeys) update(ctx context.Context) error {
+	candidate := make([]byte, 32)
+	if _, err := rand.Read(candidate); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	result, err := t.redisClient.RunScript(ctx, rotateTicketKeysScript, []string{t.key},
+		now.UnixMilli(), t.rotation.Milliseconds(), t.lifetime.Milliseconds(), hex.EncodeToString(candidate))
+	if err != nil {
+		return err
+	}
+
+	encoded, ok := result.([]any)
+	if !ok || len(encoded) == 0 {
+		return errors.New("no ticket keys returned")
+	}
+
+	keys := make([][32]byte, 0, len(encoded))
+	for _, value := range encoded {
+		decoded, err := hex.DecodeString(value.(string))
+		if err != nil || len(decoded) != 32 {
+			return errors.New("invalid ticket key in " + t.key)
+		}
+		keys = append(keys, [32]byte(decoded))
+	}
+
+	t.tlsConfig.SetSessionTicketKeys(keys)
+
+	if newest := encoded[0].(string); newest != t.newest {
+		t.newest = newest
+		t.logger.Info("Session ticket keys updated", zap.Int("keys", len(keys)))
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"go/common"
+)
+
+const adminRequestTimeout = 10 * time.Second
+
+// adminClient calls the suction-server admin HTTP API
+type adminClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (c *cli) newAdminClient(config *common.Config) (*adminClient, error) {
+	address := c.adminAddress
+	if address == "" {
+		address = config.Server.Admin.ListeningAddress
+	}
+	if address == "" {
+		return nil, errors.New("admin API address is not configured, set server.admin.listening_address or --admin-address")
+	}
+
+	token := c.adminToken
+	if token == "" {
+		token = config.Server.Admin.Token
+	}
+
+	return &adminClient{
+		baseURL: "http://" + address,
+		token:   token,
+		client:  &http.Client{Timeout: adminRequestTimeout},
+	}, nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out when set
+func (a *adminClient) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		failure := struct {
+			Error string `json:"error"`
+		}{}
+		json.NewDecoder(response.Body).Decode(&failure)
+		if failure.Error == "" {
+			failure.Error = response.Status
+		}
+
+		return errors.New("admin API " + method + " " + path + ": " + failure.Error)
+	}
+
+	if out == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
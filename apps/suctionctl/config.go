@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"go/common"
+
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand(c *cli) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Validate configuration",
+	}
+
+	command.AddCommand(newConfigCheckCommand(c), newConfigPushCommand(c))
+
+	return command
+}
+
+func newConfigCheckCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "check [file...]",
+		Short: "Validate config files against the environment, or the active configuration without arguments",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+
+			if len(args) == 0 {
+				config, err := c.loadConfig()
+				if err != nil {
+					fmt.Fprintln(out, err)
+					return errors.New("configuration is invalid")
+				}
+
+				fmt.Fprintf(out, "configuration is valid (file %q, environment %s)\n", config.File, config.Env)
+
+				return nil
+			}
+
+			failed := 0
+			for _, path := range args {
+				if _, err := common.LoadConfig(path); err != nil {
+					failed++
+					fmt.Fprintf(out, "%s: invalid\n%v\n", path, err)
+					continue
+				}
+
+				fmt.Fprintf(out, "%s: valid\n", path)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d config files are invalid", failed, len(args))
+			}
+
+			return nil
+		},
+	}
+}
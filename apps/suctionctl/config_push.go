@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go/common"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+func newConfigPushCommand(c *cli) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "push",
+		Short: "Manage the configuration documents the server pushes to clients",
+	}
+
+	command.AddCommand(newConfigPushShowCommand(c), newConfigPushSetCommand(c), newConfigPushAssignCommand(c))
+
+	return command
+}
+
+// configTarget selects the document of a client or of a group
+type configTarget struct {
+	clientID string
+	group    string
+}
+
+func (t *configTarget) addFlags(command *cobra.Command) {
+	command.Flags().StringVar(&t.clientID, "client", "", "document of a single client")
+	command.Flags().StringVar(&t.group, "group", "", "document of a client group")
+	command.MarkFlagsMutuallyExclusive("client", "group")
+	command.MarkFlagsOneRequired("client", "group")
+}
+
+func (t *configTarget) key(prefix string) string {
+	if t.clientID != "" {
+		return common.ClientConfigKey(prefix, t.clientID)
+	}
+
+	return common.GroupConfigKey(prefix, t.group)
+}
+
+func newConfigPushShowCommand(c *cli) *cobra.Command {
+	target := &configTarget{}
+
+	command := &cobra.Command{
+		Use:   "show",
+		Short: "Print the document of a client or group",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			key := target.key(config.Redis.ConfigKeyPrefix)
+			data, err := client.Get(cmd.Context(), key).Result()
+			if errors.Is(err, redis.Nil) {
+				return errors.New("no document at " + key)
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), data)
+
+			return nil
+		},
+	}
+
+	target.addFlags(command)
+
+	return command
+}
+
+func newConfigPushSetCommand(c *cli) *cobra.Command {
+	var (
+		target   = &configTarget{}
+		document common.ConfigDocument
+	)
+
+	command := &cobra.Command{
+		Use:   "set",
+		Short: "Write the document of a client or group, which connected clients receive within the refresh interval",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := document.Message(); err != nil {
+				return err
+			}
+
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			data, err := json.Marshal(document)
+			if err != nil {
+				return err
+			}
+
+			key := target.key(config.Redis.ConfigKeyPrefix)
+			if err := client.Set(cmd.Context(), key, data, 0).Err(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: %s\n", key, data)
+
+			return nil
+		},
+	}
+
+	target.addFlags(command)
+	command.Flags().Uint64Var(&document.Version, "version", 0, "document version, clients apply each version once")
+	command.Flags().StringVar(&document.FlushInterval, "flush-interval", "", "flush interval, e.g. 5s")
+	command.Flags().Uint32Var(&document.PoolMaxItems, "pool-max-items", 0, "data pool capacity")
+	command.MarkFlagRequired("version")
+
+	return command
+}
+
+func newConfigPushAssignCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "assign <client_id> <group>",
+		Short: "Put a client in a group, or back in the default group with an empty group",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			key := common.ClientGroupsKey(config.Redis.ConfigKeyPrefix)
+			if args[1] == "" {
+				err = client.HDel(cmd.Context(), key, args[0]).Err()
+			} else {
+				err = client.HSet(cmd.Context(), key, args[0], args[1]).Err()
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "client %s assigned to group %q\n", args[0], args[1])
+
+			return nil
+		},
+	}
+}
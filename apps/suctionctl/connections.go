@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+type connectionView struct {
+	ID            string    `json:"id"`
+	ClientID      string    `json:"client_id"`
+	RemoteAddr    string    `json:"remote_addr"`
+	ConnectedAt   time.Time `json:"connected_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
+	Streams       int64     `json:"streams"`
+	Frames        uint64    `json:"frames"`
+	Bytes         uint64    `json:"bytes"`
+	Records       uint64    `json:"records"`
+	Rejected      uint64    `json:"rejected"`
+	ConfigVersion uint64    `json:"config_version"`
+}
+
+type closeRequest struct {
+	Code   *uint64 `json:"code,omitempty"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+type controlRequest struct {
+	Command       string `json:"command"`
+	FlushInterval string `json:"flush_interval,omitempty"`
+	Duration      string `json:"duration,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+func newConnectionsCommand(c *cli) *cobra.Command {
+	command := &cobra.Command{
+		Use:     "connections",
+		Aliases: []string{"conn"},
+		Short:   "List and close live server connections",
+	}
+
+	command.AddCommand(newConnectionsListCommand(c), newConnectionsKickCommand(c), newConnectionsControlCommand(c))
+
+	return command
+}
+
+func newConnectionsListCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List live connections with their stream counts and stats",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			admin, err := c.newAdminClient(config)
+			if err != nil {
+				return err
+			}
+
+			connections := []connectionView{}
+			if err := admin.do(cmd.Context(), "GET", "/connections", nil, &connections); err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tCLIENT\tREMOTE\tCONNECTED\tLAST SEEN\tSTREAMS\tFRAMES\tBYTES\tRECORDS\tREJECTED\tCONFIG")
+			for _, connection := range connections {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%d\t%d\t%d\n",
+					connection.ID,
+					connection.ClientID,
+					connection.RemoteAddr,
+					humanize.Time(connection.ConnectedAt),
+					humanize.Time(connection.LastSeenAt),
+					connection.Streams,
+					connection.Frames,
+					humanize.IBytes(connection.Bytes),
+					connection.Records,
+					connection.Rejected,
+					connection.ConfigVersion)
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func newConnectionsKickCommand(c *cli) *cobra.Command {
+	var (
+		clientID string
+		code     uint64
+		reason   string
+	)
+
+	command := &cobra.Command{
+		Use:   "kick [connection-id]",
+		Short: "Close a connection, or every connection of a client with --client",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) == (clientID != "") {
+				return errors.New("pass either a connection ID or --client")
+			}
+
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			admin, err := c.newAdminClient(config)
+			if err != nil {
+				return err
+			}
+
+			request := closeRequest{Reason: reason}
+			if cmd.Flags().Changed("code") {
+				request.Code = &code
+			}
+
+			if clientID != "" {
+				result := struct {
+					Closed int `json:"closed"`
+				}{}
+				if err := admin.do(cmd.Context(), "POST", "/clients/"+url.PathEscape(clientID)+"/close", request, &result); err != nil {
+					return err
+				}
+
+				fmt.Fprintf(cmd.OutOrStdout(), "closed %d connection(s) of client %s\n", result.Closed, clientID)
+
+				return nil
+			}
+
+			if err := admin.do(cmd.Context(), "POST", "/connections/"+url.PathEscape(args[0])+"/close", request, nil); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "closed connection %s\n", args[0])
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&clientID, "client", "", "close every connection of this client ID")
+	command.Flags().Uint64Var(&code, "code", 0, "QUIC application error code: 0 (no_error), 260 (server_busy), 261 (admin_closed) or 262 (draining), defaults to admin_closed")
+	command.Flags().StringVar(&reason, "reason", "", "reason sent to the client")
+
+	return command
+}
+
+func newConnectionsControlCommand(c *cli) *cobra.Command {
+	var (
+		clientID string
+		request  controlRequest
+	)
+
+	command := &cobra.Command{
+		Use:   "control slow_down|flush|reconnect [connection-id]",
+		Short: "Tell a client to slow down, flush its pool now or reconnect, or every connection of a client with --client",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 2) == (clientID != "") {
+				return errors.New("pass either a connection ID or --client")
+			}
+
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			admin, err := c.newAdminClient(config)
+			if err != nil {
+				return err
+			}
+
+			request.Command = args[0]
+
+			path := "/clients/" + url.PathEscape(clientID) + "/control"
+			if len(args) == 2 {
+				path = "/connections/" + url.PathEscape(args[1]) + "/control"
+			}
+
+			result := struct {
+				Connections int `json:"connections"`
+			}{}
+			if err := admin.do(cmd.Context(), "POST", path, request, &result); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "sent %s to %d connection(s)\n", request.Command, result.Connections)
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&clientID, "client", "", "send to every connection of this client ID")
+	command.Flags().StringVar(&request.FlushInterval, "interval", "", "flush interval during a slow down, e.g. 5s")
+	command.Flags().StringVar(&request.Duration, "duration", "", "how long a slow down lasts, 1m by default")
+	command.Flags().StringVar(&request.Endpoint, "endpoint", "", "endpoint to reconnect to, host:port")
+	command.Flags().StringVar(&request.Reason, "reason", "", "reason sent to the client")
+
+	return command
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type drainRequest struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+func newDrainCommand(c *cli) *cobra.Command {
+	var request drainRequest
+
+	command := &cobra.Command{
+		Use:   "drain",
+		Short: "Drain the server ahead of a restart: clients finish their batches and reconnect elsewhere",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			admin, err := c.newAdminClient(config)
+			if err != nil {
+				return err
+			}
+
+			result := struct {
+				Connections int `json:"connections"`
+			}{}
+			if err := admin.do(cmd.Context(), "POST", "/drain", request, &result); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "draining %d connection(s)", result.Connections)
+			if request.Endpoint != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), " to %s", request.Endpoint)
+			}
+			fmt.Fprintln(cmd.OutOrStdout())
+
+			return nil
+		},
+	}
+
+	command.Flags().StringVar(&request.Endpoint, "redirect", "", "endpoint the clients reconnect to, host:port")
+	command.Flags().StringVar(&request.Reason, "reason", "", "reason sent to the clients")
+
+	return command
+}
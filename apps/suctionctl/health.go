@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go/common"
+
+	"github.com/quic-go/quic-go"
+	"github.com/spf13/cobra"
+)
+
+type healthView struct {
+	Status      string           `json:"status"`
+	Instance    string           `json:"instance"`
+	Connections int              `json:"connections"`
+	Paused      bool             `json:"paused"`
+	Draining    bool             `json:"draining"`
+	Sinks       []sinkStatusView `json:"sinks"`
+}
+
+type sinkStatusView struct {
+	Name        string    `json:"name"`
+	Written     uint64    `json:"written"`
+	Failed      uint64    `json:"failed"`
+	Failing     bool      `json:"failing"`
+	LastError   string    `json:"last_error"`
+	LastErrorAt time.Time `json:"last_error_at"`
+}
+
+func newHealthCommand(c *cli) *cobra.Command {
+	var timeout time.Duration
+
+	command := &cobra.Command{
+		Use:   "health",
+		Short: "Check that the server accepts QUIC handshakes and report its admin status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			healthy := true
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+
+			handshake, err := probeHandshake(ctx, config)
+			if err != nil {
+				healthy = false
+				fmt.Fprintf(out, "quic      %s  FAILED  %v\n", config.Client.ConnectionAddress, err)
+			} else {
+				fmt.Fprintf(out, "quic      %s  ok  handshake %s\n", config.Client.ConnectionAddress, handshake.Round(time.Millisecond))
+			}
+
+			admin, err := c.newAdminClient(config)
+			if err != nil {
+				fmt.Fprintf(out, "admin     skipped  %v\n", err)
+			} else {
+				health := healthView{}
+				if err := admin.do(ctx, "GET", "/health", nil, &health); err != nil {
+					healthy = false
+					fmt.Fprintf(out, "admin     FAILED  %v\n", err)
+				} else {
+					healthy = healthy && health.Status == "ok"
+					fmt.Fprintf(out, "server    %s  status %s  connections %d  paused %t  draining %t\n", health.Instance, health.Status, health.Connections, health.Paused, health.Draining)
+					for _, sink := range health.Sinks {
+						fmt.Fprintf(out, "sink      %s  written %d  failed %d  failing %t", sink.Name, sink.Written, sink.Failed, sink.Failing)
+						if sink.LastError != "" {
+							fmt.Fprintf(out, "  last error at %s: %s", sink.LastErrorAt.Format(time.RFC3339), sink.LastError)
+						}
+						fmt.Fprintln(out)
+					}
+				}
+			}
+
+			if !healthy {
+				return errors.New("server is not healthy")
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "timeout of the checks")
+
+	return command
+}
+
+// probeHandshake dials the server and returns the time the QUIC and TLS handshake took
+func probeHandshake(ctx context.Context, config *common.Config) (time.Duration, error) {
+	tlsConfig, err := common.LoadClientTLS(config)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	conn, err := quic.DialAddr(ctx, config.Client.ConnectionAddress, tlsConfig, common.NewClientQuicConfig(config.Client))
+	if err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start)
+
+	conn.CloseWithError(common.ErrorCodeNoError, "health check")
+
+	return elapsed, nil
+}
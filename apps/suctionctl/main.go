@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+
+	"go/common"
+
+	"github.com/spf13/cobra"
+)
+
+// cli holds the global flags shared by every subcommand
+type cli struct {
+	configFile   string
+	adminAddress string
+	adminToken   string
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	c := &cli{}
+
+	root := &cobra.Command{
+		Use:          "suctionctl",
+		Short:        "Inspect and operate suction servers",
+		SilenceUsage: true,
+	}
+
+	root.PersistentFlags().StringVar(&c.configFile, "config", "", "config file, defaults to $"+common.ConfigFileEnv)
+	root.PersistentFlags().StringVar(&c.adminAddress, "admin-address", "", "admin API address, defaults to server.admin.listening_address")
+	root.PersistentFlags().StringVar(&c.adminToken, "admin-token", "", "admin API token, defaults to server.admin.token")
+
+	root.AddCommand(
+		newHealthCommand(c),
+		newConnectionsCommand(c),
+		newRecordsCommand(c),
+		newRedisCommand(c),
+		newConfigCommand(c),
+		newStatusCommand(c),
+		newSendCommand(c),
+		newDrainCommand(c),
+	)
+
+	return root
+}
+
+// loadConfig resolves the configuration the same way the server and client do
+func (c *cli) loadConfig() (*common.Config, error) {
+	if c.configFile != "" {
+		if err := os.Setenv(common.ConfigFileEnv, c.configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return common.NewConfig()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go/pb"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func newRecordsCommand(c *cli) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "records",
+		Short: "Inspect records written to the Redis record stream",
+	}
+
+	command.AddCommand(newRecordsTailCommand(c))
+
+	return command
+}
+
+func newRecordsTailCommand(c *cli) *cobra.Command {
+	var (
+		count  int
+		asJSON bool
+	)
+
+	command := &cobra.Command{
+		Use:   "tail",
+		Short: "Follow new records and print them decoded",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+			lastID := "$"
+			printed := 0
+
+			for count == 0 || printed < count {
+				streams, err := client.XRead(ctx, &redis.XReadArgs{
+					Streams: []string{config.Redis.RecordStream, lastID},
+					Block:   5 * time.Second,
+				}).Result()
+				if errors.Is(err, redis.Nil) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				for _, message := range streams[0].Messages {
+					lastID = message.ID
+					if err := printRecord(out, message, asJSON); err != nil {
+						return err
+					}
+
+					printed++
+					if count > 0 && printed >= count {
+						break
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().IntVarP(&count, "count", "n", 0, "stop after this many records, 0 follows forever")
+	command.Flags().BoolVar(&asJSON, "json", false, "print the full decoded message as JSON")
+
+	return command
+}
+
+// printRecord prints a stream entry written by the Redis stream or dead letter sink
+func printRecord(out io.Writer, message redis.XMessage, asJSON bool) error {
+	data := &pb.ClientData{}
+	raw, _ := message.Values["data"].(string)
+	if err := proto.Unmarshal([]byte(raw), data); err != nil {
+		return errors.New("Failed to decode record " + message.ID + ": " + err.Error())
+	}
+
+	receivedAt := ""
+	if millis, err := strconv.ParseInt(fmt.Sprint(message.Values["received_at"]), 10, 64); err == nil {
+		receivedAt = time.UnixMilli(millis).Format(time.RFC3339Nano)
+	}
+
+	fmt.Fprintf(out, "%s  %s  client=%s  connection=%s  stream=%v  sequence=%d  readings=%d",
+		message.ID, receivedAt, message.Values["client_id"], message.Values["connection_id"],
+		message.Values["stream_id"], data.Sequence, len(data.SensorReadings))
+	if sink, ok := message.Values["sink"]; ok {
+		fmt.Fprintf(out, "  sink=%s  error=%q", sink, message.Values["error"])
+	}
+	fmt.Fprintln(out)
+
+	if asJSON {
+		encoded, err := protojson.Marshal(data)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(encoded))
+	}
+
+	return nil
+}
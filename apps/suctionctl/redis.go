@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go/common"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+)
+
+func newRedisClient(config *common.Config) (*redis.Client, error) {
+	opts, err := redis.ParseURL(config.Redis.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, errors.New("Failed to connect to Redis: " + err.Error())
+	}
+
+	return client, nil
+}
+
+func newRedisCommand(c *cli) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "redis",
+		Short: "Inspect the Redis streams written by the server",
+	}
+
+	command.AddCommand(newRedisStreamsCommand(c), newRedisDeadLettersCommand(c))
+
+	return command
+}
+
+func newRedisStreamsCommand(c *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "streams",
+		Short: "Show the length and consumer group lag of the record and dead letter streams",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ctx := cmd.Context()
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "STREAM\tLENGTH\tFIRST\tLAST\tGROUPS")
+
+			for _, stream := range []string{config.Redis.RecordStream, config.Redis.DeadLetterStream} {
+				info, err := client.XInfoStream(ctx, stream).Result()
+				if err != nil {
+					if strings.Contains(err.Error(), "no such key") {
+						fmt.Fprintf(w, "%s\t0\t-\t-\t-\n", stream)
+						continue
+					}
+					return err
+				}
+
+				groups, err := client.XInfoGroups(ctx, stream).Result()
+				if err != nil {
+					return err
+				}
+
+				descriptions := make([]string, 0, len(groups))
+				for _, group := range groups {
+					descriptions = append(descriptions, fmt.Sprintf("%s(consumers=%d pending=%d lag=%d)", group.Name, group.Consumers, group.Pending, group.Lag))
+				}
+				if len(descriptions) == 0 {
+					descriptions = append(descriptions, "-")
+				}
+
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", stream, info.Length, entryID(info.FirstEntry), entryID(info.LastEntry), strings.Join(descriptions, " "))
+			}
+
+			return w.Flush()
+		},
+	}
+}
+
+func newRedisDeadLettersCommand(c *cli) *cobra.Command {
+	var (
+		count  int64
+		asJSON bool
+	)
+
+	command := &cobra.Command{
+		Use:   "dead-letters",
+		Short: "Print the most recent records sinks failed to store",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			messages, err := client.XRevRangeN(cmd.Context(), config.Redis.DeadLetterStream, "+", "-", count).Result()
+			if err != nil {
+				return err
+			}
+
+			for _, message := range messages {
+				if err := printRecord(cmd.OutOrStdout(), message, asJSON); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().Int64VarP(&count, "count", "n", 20, "number of entries to print")
+	command.Flags().BoolVar(&asJSON, "json", false, "print the full decoded message as JSON")
+
+	return command
+}
+
+func entryID(message redis.XMessage) string {
+	if message.ID == "" {
+		return "-"
+	}
+
+	return message.ID
+}
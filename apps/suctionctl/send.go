@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"go/common"
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+)
+
+func newSendCommand(c *cli) *cobra.Command {
+	var (
+		batches  int
+		readings int
+		interval time.Duration
+		timeout  time.Duration
+	)
+
+	command := &cobra.Command{
+		Use:   "send",
+		Short: "Send synthetic data batches with the client credentials and print the acks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			tlsConfig, err := common.LoadClientTLS(config)
+			if err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			out := cmd.OutOrStdout()
+
+			conn, err := quic.DialAddr(ctx, config.Client.ConnectionAddress, tlsConfig, common.NewClientQuicConfig(config.Client))
+			if err != nil {
+				return err
+			}
+			defer conn.CloseWithError(common.ErrorCodeNoError, "suctionctl send done")
+
+			stream, err := conn.OpenStreamSync(ctx)
+			if err != nil {
+				return err
+			}
+			defer stream.Close()
+
+			reader := common.NewFrameReader(stream, common.FrameHeaderSize, common.ControlFrameMaxSize)
+
+			response, err := common.Authenticate(stream, reader, config.Client.Auth)
+			if err != nil {
+				return err
+			}
+			if response != nil {
+				fmt.Fprintf(out, "authenticated as %s\n", response.ClientId)
+			}
+
+			failed := 0
+
+			for sequence := uint64(1); sequence <= uint64(batches); sequence++ {
+				data := &pb.ClientData{
+					Sequence:       sequence,
+					Timestamp:      time.Now().Unix(),
+					Message:        fmt.Sprintf("suctionctl batch %d", sequence),
+					SensorReadings: make([]float32, readings),
+				}
+				for i := range data.SensorReadings {
+					data.SensorReadings[i] = rand.Float32() * 100.0
+				}
+
+				payload, size, err := common.EncodeClientData(data)
+				if err != nil {
+					return err
+				}
+
+				start := time.Now()
+				if err := common.WriteFrame(stream, common.FrameClientData, payload); err != nil {
+					return err
+				}
+
+				stream.SetReadDeadline(time.Now().Add(timeout))
+				ack, err := readAck(reader)
+				if err != nil {
+					return errors.New("Failed to read ack: " + err.Error())
+				}
+
+				if ack.Status != pb.AckStatus_ACK_STATUS_OK {
+					failed++
+				}
+
+				fmt.Fprintf(out, "sequence %d  %d bytes (%d compressed)  %s  %s", ack.Sequence, size, len(payload), ack.Status, time.Since(start).Round(time.Microsecond))
+				if ack.Message != "" {
+					fmt.Fprintf(out, "  %s", ack.Message)
+				}
+				fmt.Fprintln(out)
+
+				if sequence < uint64(batches) {
+					time.Sleep(interval)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d batches were rejected", failed, batches)
+			}
+
+			return nil
+		},
+	}
+
+	command.Flags().IntVarP(&batches, "batches", "n", 1, "number of batches to send")
+	command.Flags().IntVar(&readings, "readings", 100, "sensor readings per batch")
+	command.Flags().DurationVar(&interval, "interval", 100*time.Millisecond, "pause between batches")
+	command.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "time to wait for each ack")
+
+	return command
+}
+
+// readAck reads frames until the ack of the batch just sent. Backpressure, control and drain frames
+// the server writes to the stream in between are skipped; an error frame means no ack follows.
+func readAck(reader *common.FrameReader) (*pb.Ack, error) {
+	for {
+		frame, err := reader.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch frame.Type {
+		case common.FrameAck:
+			ack := &pb.Ack{}
+			err := proto.Unmarshal(frame.Payload, ack)
+			frame.Release()
+
+			return ack, err
+		case common.FrameError:
+			report := &pb.Error{}
+			err := proto.Unmarshal(frame.Payload, report)
+			frame.Release()
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, errors.New("server reported " + common.ErrorCodeName(quic.ApplicationErrorCode(report.Code)) + ": " + report.Message)
+		default:
+			frame.Release()
+		}
+	}
+}
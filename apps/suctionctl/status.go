@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+)
+
+// clientStatusView is the status hash the server keeps for every client
+type clientStatusView struct {
+	ClientID         string  `json:"client_id" redis:"client_id"`
+	ConnectionID     string  `json:"connection_id" redis:"connection_id"`
+	Instance         string  `json:"instance" redis:"instance"`
+	ReportedAt       int64   `json:"reported_at" redis:"reported_at"`
+	Version          string  `json:"version" redis:"version"`
+	StartedAt        int64   `json:"started_at" redis:"started_at"`
+	UptimeSeconds    uint64  `json:"uptime_seconds" redis:"uptime_seconds"`
+	PoolItems        uint32  `json:"pool_items" redis:"pool_items"`
+	PoolMaxItems     uint32  `json:"pool_max_items" redis:"pool_max_items"`
+	SpoolBytes       uint64  `json:"spool_bytes" redis:"spool_bytes"`
+	DroppedItems     uint64  `json:"dropped_items" redis:"dropped_items"`
+	RejectedBatches  uint64  `json:"rejected_batches" redis:"rejected_batches"`
+	LastError        string  `json:"last_error" redis:"last_error"`
+	LastErrorAt      int64   `json:"last_error_at" redis:"last_error_at"`
+	ConfigVersion    uint64  `json:"config_version" redis:"config_version"`
+	Hostname         string  `json:"hostname" redis:"hostname"`
+	CPUPercent       float64 `json:"cpu_percent" redis:"cpu_percent"`
+	MemoryTotalBytes uint64  `json:"memory_total_bytes" redis:"memory_total_bytes"`
+	MemoryUsedBytes  uint64  `json:"memory_used_bytes" redis:"memory_used_bytes"`
+	DiskPath         string  `json:"disk_path" redis:"disk_path"`
+	DiskTotalBytes   uint64  `json:"disk_total_bytes" redis:"disk_total_bytes"`
+	DiskUsedBytes    uint64  `json:"disk_used_bytes" redis:"disk_used_bytes"`
+	StaleSince       int64   `json:"stale_since,omitempty" redis:"stale_since,omitempty"`
+}
+
+func newStatusCommand(c *cli) *cobra.Command {
+	var asJSON bool
+
+	command := &cobra.Command{
+		Use:   "status [client id]",
+		Short: "Show the latest status reported by clients, most recent first",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := c.loadConfig()
+			if err != nil {
+				return err
+			}
+
+			client, err := newRedisClient(config)
+			if err != nil {
+				return err
+			}
+			defer client.Close()
+
+			ctx := cmd.Context()
+			clientIDs := args
+			if len(clientIDs) == 0 {
+				if clientIDs, err = client.ZRevRange(ctx, config.Redis.StatusKeyPrefix, 0, -1).Result(); err != nil {
+					return err
+				}
+			}
+
+			statuses := make([]clientStatusView, 0, len(clientIDs))
+			for _, clientID := range clientIDs {
+				result := client.HGetAll(ctx, config.Redis.StatusKeyPrefix+":"+clientID)
+				if err := result.Err(); err != nil {
+					return err
+				}
+				if len(result.Val()) == 0 {
+					// Expired since it was indexed
+					continue
+				}
+
+				status := clientStatusView{}
+				if err := result.Scan(&status); err != nil {
+					return err
+				}
+				statuses = append(statuses, status)
+			}
+
+			if len(args) > 0 && len(statuses) == 0 {
+				return errors.New("No status reported by client " + args[0])
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(statuses)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CLIENT\tHOST\tVERSION\tREPORTED\tUPTIME\tPOOL\tSPOOL\tDROPPED\tREJECTED\tCPU\tMEMORY\tDISK\tLAST ERROR")
+			for _, status := range statuses {
+				reported := humanize.Time(time.UnixMilli(status.ReportedAt))
+				if status.StaleSince != 0 {
+					reported += " (stale)"
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%s\t%d\t%d\t%.1f%%\t%s\t%s\t%s\n",
+					status.ClientID,
+					status.Hostname,
+					status.Version,
+					reported,
+					time.Duration(status.UptimeSeconds)*time.Second,
+					status.PoolItems,
+					status.PoolMaxItems,
+					humanize.IBytes(status.SpoolBytes),
+					status.DroppedItems,
+					status.RejectedBatches,
+					status.CPUPercent,
+					hostUsage(status.MemoryUsedBytes, status.MemoryTotalBytes),
+					hostUsage(status.DiskUsedBytes, status.DiskTotalBytes),
+					statusLastError(status))
+			}
+
+			return w.Flush()
+		},
+	}
+
+	command.Flags().BoolVar(&asJSON, "json", false, "print the full status as JSON")
+
+	return command
+}
+
+func hostUsage(used, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+
+	return humanize.IBytes(used) + "/" + humanize.IBytes(total)
+}
+
+func statusLastError(status clientStatusView) string {
+	if status.LastError == "" {
+		return "-"
+	}
+
+	return status.LastError + " (" + humanize.Time(time.UnixMilli(status.LastErrorAt)) + ")"
+}
@@ -0,0 +1,209 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	certificateDebounce      = time.Second
+	certificateCheckInterval = time.Hour
+)
+
+// CertificateReloader keeps a certificate in sync with its files and swaps it atomically on change,
+// so new handshakes use the renewed certificate while established connections stay open.
+type CertificateReloader struct {
+	certFile    string
+	keyFile     string
+	warnBefore  time.Duration
+	logger      *zap.Logger
+	certificate atomic.Pointer[tls.Certificate]
+	expiry      prometheus.Gauge
+	reloads     *prometheus.CounterVec
+}
+
+// NewCertificateReloader loads the certificate and watches its files with the application lifecycle
+func NewCertificateReloader(name, certFile, keyFile string, warnBefore time.Duration, logger *zap.Logger, metrics *Metrics, lifecycle fx.Lifecycle) (*CertificateReloader, error) {
+	labels := prometheus.Labels{"certificate": name}
+	reloader := &CertificateReloader{
+		certFile:   certFile,
+		keyFile:    keyFile,
+		warnBefore: warnBefore,
+		logger:     logger.With(zap.String("certificate", name)),
+		expiry: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "tls",
+			Name:        "certificate_expiry_timestamp_seconds",
+			Help:        "Expiry time of the loaded certificate in unix seconds.",
+			ConstLabels: labels,
+		}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   metricsNamespace,
+			Subsystem:   "tls",
+			Name:        "certificate_reloads_total",
+			Help:        "Certificate reload attempts by result.",
+			ConstLabels: labels,
+		}, []string{"result"}),
+	}
+
+	if err := metrics.Registry.Register(reloader.expiry); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(reloader.reloads); err != nil {
+		return nil, err
+	}
+
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range uniqueDirs(certFile, keyFile) {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	done := make(chan struct{})
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go reloader.watch(watcher, done)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(done)
+
+			return watcher.Close()
+		},
+	})
+
+	return reloader, nil
+}
+
+// GetCertificate returns the current certificate for tls.Config.GetCertificate
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
+
+// GetClientCertificate returns the current certificate for tls.Config.GetClientCertificate
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
+
+// NotAfter returns the expiry of the current certificate
+func (r *CertificateReloader) NotAfter() time.Time {
+	return r.certificate.Load().Leaf.NotAfter
+}
+
+func (r *CertificateReloader) load() error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		r.reloads.WithLabelValues("failure").Inc()
+		return errors.New("Could not load certificate: " + err.Error())
+	}
+
+	if certificate.Leaf == nil {
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			r.reloads.WithLabelValues("failure").Inc()
+			return errors.New("Could not parse certificate: " + err.Error())
+		}
+		certificate.Leaf = leaf
+	}
+
+	if current := r.certificate.Load(); current != nil && bytes.Equal(current.Leaf.Raw, certificate.Leaf.Raw) {
+		return nil
+	}
+
+	r.certificate.Store(&certificate)
+	r.expiry.Set(float64(certificate.Leaf.NotAfter.Unix()))
+	r.reloads.WithLabelValues("success").Inc()
+
+	r.logger.Info("Certificate loaded",
+		zap.String("subject", certificate.Leaf.Subject.String()),
+		zap.String("serial", certificate.Leaf.SerialNumber.String()),
+		zap.Time("not_after", certificate.Leaf.NotAfter))
+
+	r.checkExpiry()
+
+	return nil
+}
+
+func (r *CertificateReloader) checkExpiry() {
+	notAfter := r.NotAfter()
+	remaining := time.Until(notAfter)
+
+	switch {
+	case remaining <= 0:
+		r.logger.Error("Certificate has expired", zap.Time("not_after", notAfter))
+	case remaining <= r.warnBefore:
+		r.logger.Warn("Certificate expires soon", zap.Time("not_after", notAfter), zap.Duration("remaining", remaining))
+	}
+}
+
+func (r *CertificateReloader) watch(watcher *fsnotify.Watcher, done <-chan struct{}) {
+	debounce := time.NewTimer(certificateDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	check := time.NewTicker(certificateCheckInterval)
+	defer check.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			debounce.Reset(certificateDebounce)
+		case <-debounce.C:
+			if err := r.load(); err != nil {
+				r.logger.Error("Certificate reload failed, keeping current certificate", zap.Error(err))
+			}
+		case <-check.C:
+			r.checkExpiry()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			r.logger.Error("Certificate watcher error", zap.Error(err))
+		}
+	}
+}
+
+func uniqueDirs(paths ...string) []string {
+	seen := make(map[string]bool, len(paths))
+	dirs := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
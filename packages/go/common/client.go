@@ -0,0 +1,92 @@
+package common
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"go/pb"
+
+	"github.com/quic-go/quic-go"
+)
+
+// ErrAuthDenied is returned when the server rejected the credential
+var ErrAuthDenied = errors.New("authentication denied")
+
+// NewClientQuicConfig builds the QUIC settings used to dial the server
+func NewClientQuicConfig(client ClientConfig) *quic.Config {
+	return &quic.Config{
+		MaxIdleTimeout:                 client.MaxIdleTimeout,
+		KeepAlivePeriod:                client.KeepAlivePeriod,
+		MaxIncomingStreams:             client.MaxIncomingStreams,
+		MaxIncomingUniStreams:          client.MaxIncomingUniStreams,
+		InitialStreamReceiveWindow:     client.InitialStreamReceiveWindow.Uint64(),
+		MaxStreamReceiveWindow:         client.MaxStreamReceiveWindow.Uint64(),
+		InitialConnectionReceiveWindow: client.InitialConnectionReceiveWindow.Uint64(),
+		MaxConnectionReceiveWindow:     client.MaxConnectionReceiveWindow.Uint64(),
+	}
+}
+
+// LoadClientTLS builds the client TLS configuration once, without watching the certificate files.
+// It is meant for short-lived tools; long-running clients use NewClientTLSConfig.
+func LoadClientTLS(config *Config) (*tls.Config, error) {
+	tlsConfig := config.Client.TLS
+
+	rootCAs, err := loadCertPool(tlsConfig.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTLS := &tls.Config{
+		RootCAs:            rootCAs,
+		ServerName:         tlsConfig.ServerName,
+		NextProtos:         []string{NextProto},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+	}
+
+	if tlsConfig.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(tlsConfig.CertFile, tlsConfig.KeyFile)
+		if err != nil {
+			return nil, errors.New("Could not load certificate: " + err.Error())
+		}
+		clientTLS.Certificates = []tls.Certificate{certificate}
+	}
+
+	return clientTLS, nil
+}
+
+// Authenticate sends the configured credential as the first frame of the stream and waits for the
+// server decision, read with reader. The caller keeps reading the stream with the same reader, as
+// it may have buffered the frames the server wrote after its decision. It returns a nil response
+// when no credential is configured.
+func Authenticate(stream *quic.Stream, reader *FrameReader, auth ClientAuthConfig) (*pb.AuthResponse, error) {
+	request := &pb.AuthRequest{}
+	switch {
+	case auth.APIKey != "":
+		request.Credential = &pb.AuthRequest_ApiKey{ApiKey: auth.APIKey}
+	case auth.Token != "":
+		request.Credential = &pb.AuthRequest_Token{Token: auth.Token}
+	default:
+		return nil, nil
+	}
+
+	if err := WriteMessage(stream, FrameAuthRequest, request); err != nil {
+		return nil, err
+	}
+
+	stream.SetReadDeadline(time.Now().Add(auth.Timeout))
+	defer stream.SetReadDeadline(time.Time{})
+
+	response := &pb.AuthResponse{}
+	if err := ReadMessage(reader, FrameAuthResponse, response); err != nil {
+		return nil, err
+	}
+
+	if response.Status != pb.AuthStatus_AUTH_STATUS_OK {
+		return nil, fmt.Errorf("%w: %s", ErrAuthDenied, response.Message)
+	}
+
+	return response, nil
+}
@@ -2,12 +2,18 @@ package common
 
 import (
 	"errors"
+	"fmt"
 	"os"
-	"strconv"
-
-	"go.uber.org/zap"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v11"
+	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 type Environment string
@@ -17,114 +23,425 @@ const (
 	Production Environment = "production"
 )
 
+// ConfigFileEnv is the environment variable holding the optional YAML or TOML config file path
+const ConfigFileEnv = "SUCTION_CONFIG_FILE"
+
+// Config is the typed suction configuration.
+// Values are resolved in order of defaults, config file and environment variables, then validated as a whole.
 type Config struct {
-	Env                                      Environment
-	QuicMaxIdleTimeout                       int64
-	QuicKeepAlivePeriod                      int64
-	QuicServerListeningAddress               string
-	QuicServerStreamBufferSize               int
-	QuicServerMaxIncomingStreams             int64
-	QuicServerMaxIncomingUniStreams          int64
-	QuicServerInitialStreamReceiveWindow     uint64
-	QuicServerMaxStreamReceiveWindow         uint64
-	QuicServerInitialConnectionReceiveWindow uint64
-	QuicServerMaxConnectionReceiveWindow     uint64
-	QuicClientConnectionAddress              string
-	RedisURL                                 string
-}
-
-func NewConfig(logger *zap.Logger) (*Config, error) {
-	envStr := os.Getenv("ENV")
+	Env    Environment  `yaml:"env" toml:"env" env:"ENV" validate:"required,oneof=local production"`
+	File   string       `yaml:"-" toml:"-"`
+	Log    LogConfig    `yaml:"log" toml:"log"`
+	Server ServerConfig `yaml:"server" toml:"server"`
+	Client ClientConfig `yaml:"client" toml:"client"`
+	Redis  RedisConfig  `yaml:"redis" toml:"redis"`
+}
+
+// LogConfig holds logging settings
+type LogConfig struct {
+	Level string `yaml:"level" toml:"level" env:"SUCTION_LOG_LEVEL" envDefault:"debug" validate:"oneof=debug info warn error" reload:"true"`
+}
+
+// ServerConfig holds suction-server settings
+type ServerConfig struct {
+	ListeningAddress               string           `yaml:"listening_address" toml:"listening_address" env:"SUCTION_QUIC_SERVER_LISTENING_ADDRESS" envDefault:"0.0.0.0:10002" validate:"required,hostname_port"`
+	MaxIdleTimeout                 time.Duration    `yaml:"max_idle_timeout" toml:"max_idle_timeout" env:"SUCTION_QUIC_SERVER_MAX_IDLE_TIMEOUT" envDefault:"30s" validate:"gt=0"`
+	KeepAlivePeriod                time.Duration    `yaml:"keep_alive_period" toml:"keep_alive_period" env:"SUCTION_QUIC_SERVER_KEEP_ALIVE_PERIOD" envDefault:"15s" validate:"gt=0,ltfield=MaxIdleTimeout"`
+	StreamBufferSize               ByteSize         `yaml:"stream_buffer_size" toml:"stream_buffer_size" env:"SUCTION_QUIC_SERVER_STREAM_BUFFER_SIZE" envDefault:"8KiB" validate:"gt=0"`
+	MaxIncomingStreams             int64            `yaml:"max_incoming_streams" toml:"max_incoming_streams" env:"SUCTION_QUIC_SERVER_MAX_INCOMING_STREAMS" envDefault:"1000" validate:"gt=0"`
+	MaxIncomingUniStreams          int64            `yaml:"max_incoming_uni_streams" toml:"max_incoming_uni_streams" env:"SUCTION_QUIC_SERVER_MAX_INCOMING_UNI_STREAMS" envDefault:"100" validate:"gte=0"`
+	InitialStreamReceiveWindow     ByteSize         `yaml:"initial_stream_receive_window" toml:"initial_stream_receive_window" env:"SUCTION_QUIC_SERVER_INITIAL_STREAM_RECEIVE_WINDOW" envDefault:"1MiB" validate:"gt=0,ltefield=MaxStreamReceiveWindow"`
+	MaxStreamReceiveWindow         ByteSize         `yaml:"max_stream_receive_window" toml:"max_stream_receive_window" env:"SUCTION_QUIC_SERVER_MAX_STREAM_RECEIVE_WINDOW" envDefault:"6MiB" validate:"gt=0"`
+	InitialConnectionReceiveWindow ByteSize         `yaml:"initial_connection_receive_window" toml:"initial_connection_receive_window" env:"SUCTION_QUIC_SERVER_INITIAL_CONNECTION_RECEIVE_WINDOW" envDefault:"2MiB" validate:"gt=0,ltefield=MaxConnectionReceiveWindow"`
+	MaxConnectionReceiveWindow     ByteSize         `yaml:"max_connection_receive_window" toml:"max_connection_receive_window" env:"SUCTION_QUIC_SERVER_MAX_CONNECTION_RECEIVE_WINDOW" envDefault:"15MiB" validate:"gt=0,gtefield=MaxStreamReceiveWindow"`
+	MetricsListeningAddress        string           `yaml:"metrics_listening_address" toml:"metrics_listening_address" env:"SUCTION_QUIC_SERVER_METRICS_LISTENING_ADDRESS" envDefault:"0.0.0.0:10003" validate:"omitempty,hostname_port"`
+	Sinks                          []string         `yaml:"sinks" toml:"sinks" env:"SUCTION_QUIC_SERVER_SINKS" envDefault:"redis" validate:"dive,oneof=redis"`
+	MaxFrameSize                   ByteSize         `yaml:"max_frame_size" toml:"max_frame_size" env:"SUCTION_QUIC_SERVER_MAX_FRAME_SIZE" envDefault:"4MiB" validate:"gt=0"`
+	MaxDecodedSize                 ByteSize         `yaml:"max_decoded_size" toml:"max_decoded_size" env:"SUCTION_QUIC_SERVER_MAX_DECODED_SIZE" envDefault:"0" validate:"gte=0"`
+	Auth                           ServerAuthConfig `yaml:"auth" toml:"auth"`
+	RateLimit                      RateLimitConfig  `yaml:"rate_limit" toml:"rate_limit"`
+	Admission                      AdmissionConfig  `yaml:"admission" toml:"admission"`
+	Handshake                      HandshakeConfig  `yaml:"handshake" toml:"handshake"`
+	Sessions                       SessionsConfig   `yaml:"sessions" toml:"sessions"`
+	Admin                          AdminConfig      `yaml:"admin" toml:"admin"`
+	Pipeline                       PipelineConfig   `yaml:"pipeline" toml:"pipeline"`
+	Resumption                     ResumptionConfig `yaml:"resumption" toml:"resumption"`
+	Drain                          DrainConfig      `yaml:"drain" toml:"drain"`
+	ConfigPush                     ConfigPushConfig `yaml:"config_push" toml:"config_push"`
+	Backpressure                   PressureConfig   `yaml:"backpressure" toml:"backpressure"`
+	Status                         StatusConfig     `yaml:"status" toml:"status"`
+	TLS                            ServerTLSConfig  `yaml:"tls" toml:"tls"`
+}
+
+// ServerAuthConfig holds the token authentication settings.
+// Connections already identified by a client certificate skip token authentication.
+type ServerAuthConfig struct {
+	TokenRequired bool          `yaml:"token_required" toml:"token_required" env:"SUCTION_QUIC_SERVER_AUTH_TOKEN_REQUIRED"`
+	Timeout       time.Duration `yaml:"timeout" toml:"timeout" env:"SUCTION_QUIC_SERVER_AUTH_TIMEOUT" envDefault:"5s" validate:"gt=0"`
+}
+
+// RateLimitConfig holds the per-client rate limits applied to incoming data frames.
+// Clients use the default limits unless they are assigned a tier or limits of their own.
+type RateLimitConfig struct {
+	Enabled         bool                       `yaml:"enabled" toml:"enabled" env:"SUCTION_QUIC_SERVER_RATE_LIMIT_ENABLED" reload:"true"`
+	Store           string                     `yaml:"store" toml:"store" env:"SUCTION_QUIC_SERVER_RATE_LIMIT_STORE" envDefault:"memory" validate:"oneof=memory redis"`
+	MaxViolations   int                        `yaml:"max_violations" toml:"max_violations" env:"SUCTION_QUIC_SERVER_RATE_LIMIT_MAX_VIOLATIONS" envDefault:"100" validate:"gte=0" reload:"true"`
+	ViolationWindow time.Duration              `yaml:"violation_window" toml:"violation_window" env:"SUCTION_QUIC_SERVER_RATE_LIMIT_VIOLATION_WINDOW" envDefault:"1m" validate:"gt=0" reload:"true"`
+	Default         RateLimit                  `yaml:"default" toml:"default" envPrefix:"SUCTION_QUIC_SERVER_RATE_LIMIT_DEFAULT_"`
+	Tiers           map[string]RateLimit       `yaml:"tiers" toml:"tiers" validate:"dive" reload:"true"`
+	Clients         map[string]ClientRateLimit `yaml:"clients" toml:"clients" validate:"dive" reload:"true"`
+}
+
+// RateLimit holds token bucket limits and a daily quota. A zero value means unlimited.
+type RateLimit struct {
+	MessagesPerSecond float64  `yaml:"messages_per_second" toml:"messages_per_second" env:"MESSAGES_PER_SECOND" validate:"gte=0" reload:"true"`
+	MessagesBurst     int      `yaml:"messages_burst" toml:"messages_burst" env:"MESSAGES_BURST" validate:"gte=0" reload:"true"`
+	BytesPerSecond    ByteSize `yaml:"bytes_per_second" toml:"bytes_per_second" env:"BYTES_PER_SECOND" reload:"true"`
+	BytesBurst        ByteSize `yaml:"bytes_burst" toml:"bytes_burst" env:"BYTES_BURST" reload:"true"`
+	DailyQuota        ByteSize `yaml:"daily_quota" toml:"daily_quota" env:"DAILY_QUOTA" reload:"true"`
+}
+
+// ClientRateLimit assigns a client to a tier. Non-zero limits override the ones of the tier.
+type ClientRateLimit struct {
+	Tier      string `yaml:"tier" toml:"tier"`
+	RateLimit `yaml:",inline" toml:",inline"`
+}
+
+// AdmissionConfig caps the connections accepted by the server. A zero limit disables the cap.
+// Per-IP limits group addresses by subnet prefix length, so a /24 or /64 counts as a single source.
+type AdmissionConfig struct {
+	MaxConnections          int     `yaml:"max_connections" toml:"max_connections" env:"SUCTION_QUIC_SERVER_ADMISSION_MAX_CONNECTIONS" envDefault:"10000" validate:"gte=0" reload:"true"`
+	MaxConnectionsPerIP     int     `yaml:"max_connections_per_ip" toml:"max_connections_per_ip" env:"SUCTION_QUIC_SERVER_ADMISSION_MAX_CONNECTIONS_PER_IP" envDefault:"100" validate:"gte=0" reload:"true"`
+	IPv4PrefixLength        int     `yaml:"ipv4_prefix_length" toml:"ipv4_prefix_length" env:"SUCTION_QUIC_SERVER_ADMISSION_IPV4_PREFIX_LENGTH" envDefault:"32" validate:"min=1,max=32"`
+	IPv6PrefixLength        int     `yaml:"ipv6_prefix_length" toml:"ipv6_prefix_length" env:"SUCTION_QUIC_SERVER_ADMISSION_IPV6_PREFIX_LENGTH" envDefault:"64" validate:"min=1,max=128"`
+	NewConnectionsPerSecond float64 `yaml:"new_connections_per_second" toml:"new_connections_per_second" env:"SUCTION_QUIC_SERVER_ADMISSION_NEW_CONNECTIONS_PER_SECOND" envDefault:"500" validate:"gte=0" reload:"true"`
+	NewConnectionsBurst     int     `yaml:"new_connections_burst" toml:"new_connections_burst" env:"SUCTION_QUIC_SERVER_ADMISSION_NEW_CONNECTIONS_BURST" envDefault:"1000" validate:"gte=0" reload:"true"`
+}
+
+// HandshakeConfig holds source address validation and handshake rate limits, applied before
+// a connection is accepted. Retry is one of never, under_load or always. Under load, clients
+// must validate their address once unvalidated handshakes exceed retry_threshold per second.
+// Per-IP handshake limits only apply to validated addresses, as others may be spoofed.
+type HandshakeConfig struct {
+	Retry                       string        `yaml:"retry" toml:"retry" env:"SUCTION_QUIC_SERVER_HANDSHAKE_RETRY" envDefault:"under_load" validate:"oneof=never under_load always" reload:"true"`
+	RetryThreshold              float64       `yaml:"retry_threshold" toml:"retry_threshold" env:"SUCTION_QUIC_SERVER_HANDSHAKE_RETRY_THRESHOLD" envDefault:"100" validate:"gt=0" reload:"true"`
+	MaxHandshakesPerSecond      float64       `yaml:"max_handshakes_per_second" toml:"max_handshakes_per_second" env:"SUCTION_QUIC_SERVER_HANDSHAKE_MAX_HANDSHAKES_PER_SECOND" envDefault:"1000" validate:"gte=0" reload:"true"`
+	MaxHandshakesPerSecondPerIP float64       `yaml:"max_handshakes_per_second_per_ip" toml:"max_handshakes_per_second_per_ip" env:"SUCTION_QUIC_SERVER_HANDSHAKE_MAX_HANDSHAKES_PER_SECOND_PER_IP" envDefault:"10" validate:"gte=0" reload:"true"`
+	IdleTimeout                 time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SUCTION_QUIC_SERVER_HANDSHAKE_IDLE_TIMEOUT" envDefault:"5s" validate:"gt=0"`
+	MaxTokenAge                 time.Duration `yaml:"max_token_age" toml:"max_token_age" env:"SUCTION_QUIC_SERVER_HANDSHAKE_MAX_TOKEN_AGE" envDefault:"24h" validate:"gt=0"`
+}
+
+// SessionsConfig holds the settings of the connected-client registry kept in Redis.
+// The instance ID defaults to the host name.
+type SessionsConfig struct {
+	InstanceID      string        `yaml:"instance_id" toml:"instance_id" env:"SUCTION_QUIC_SERVER_SESSIONS_INSTANCE_ID"`
+	TTL             time.Duration `yaml:"ttl" toml:"ttl" env:"SUCTION_QUIC_SERVER_SESSIONS_TTL" envDefault:"30s" validate:"gt=0"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" toml:"refresh_interval" env:"SUCTION_QUIC_SERVER_SESSIONS_REFRESH_INTERVAL" envDefault:"10s" validate:"gt=0,ltfield=TTL"`
+}
+
+// AdminConfig holds the admin HTTP API settings. An empty listening address disables the API.
+// Requests must carry the token as a bearer token.
+type AdminConfig struct {
+	ListeningAddress string `yaml:"listening_address" toml:"listening_address" env:"SUCTION_QUIC_SERVER_ADMIN_LISTENING_ADDRESS" validate:"omitempty,hostname_port"`
+	Token            string `yaml:"token" toml:"token" env:"SUCTION_QUIC_SERVER_ADMIN_TOKEN" validate:"required_with=ListeningAddress,omitempty,min=16" secret:"true"`
+}
+
+// PipelineConfig sizes the ingest stages. Stream readers queue data frames for the decode workers,
+// which queue decoded records for the sink workers. While a queue is full the readers stop reading
+// and QUIC flow control pushes back on the clients. Zero decode workers starts one per CPU.
+type PipelineConfig struct {
+	DecodeQueueSize int `yaml:"decode_queue_size" toml:"decode_queue_size" env:"SUCTION_QUIC_SERVER_PIPELINE_DECODE_QUEUE_SIZE" envDefault:"1024" validate:"gt=0"`
+	DecodeWorkers   int `yaml:"decode_workers" toml:"decode_workers" env:"SUCTION_QUIC_SERVER_PIPELINE_DECODE_WORKERS" envDefault:"0" validate:"gte=0"`
+	SinkQueueSize   int `yaml:"sink_queue_size" toml:"sink_queue_size" env:"SUCTION_QUIC_SERVER_PIPELINE_SINK_QUEUE_SIZE" envDefault:"1024" validate:"gt=0"`
+	SinkWorkers     int `yaml:"sink_workers" toml:"sink_workers" env:"SUCTION_QUIC_SERVER_PIPELINE_SINK_WORKERS" envDefault:"8" validate:"gt=0"`
+}
+
+// ResumptionConfig holds the TLS session ticket and 0-RTT settings of the server, both on by default.
+// Ticket keys are shared by every instance through Redis: a new key is added every rotation interval
+// and kept for decryption until it is older than the lifetime. 0-RTT data is only acted on for
+// replay-safe frames; other frames wait for the handshake to complete.
+type ResumptionConfig struct {
+	Disabled          bool          `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_SERVER_RESUMPTION_DISABLED"`
+	Disable0RTT       bool          `yaml:"disable_0rtt" toml:"disable_0rtt" env:"SUCTION_QUIC_SERVER_RESUMPTION_DISABLE_0RTT"`
+	TicketKeyRotation time.Duration `yaml:"ticket_key_rotation" toml:"ticket_key_rotation" env:"SUCTION_QUIC_SERVER_RESUMPTION_TICKET_KEY_ROTATION" envDefault:"24h" validate:"gt=0"`
+	TicketKeyLifetime time.Duration `yaml:"ticket_key_lifetime" toml:"ticket_key_lifetime" env:"SUCTION_QUIC_SERVER_RESUMPTION_TICKET_KEY_LIFETIME" envDefault:"72h" validate:"gtfield=TicketKeyRotation"`
+	TicketKeyRefresh  time.Duration `yaml:"ticket_key_refresh" toml:"ticket_key_refresh" env:"SUCTION_QUIC_SERVER_RESUMPTION_TICKET_KEY_REFRESH" envDefault:"1m" validate:"gt=0,ltfield=TicketKeyRotation"`
+}
+
+// DrainConfig holds the graceful drain of client connections on shutdown. Clients are told to
+// reconnect, to the redirect address when set, and get up to the timeout to stop sending and
+// receive the acks of their last batches before their connections are closed.
+type DrainConfig struct {
+	Timeout         time.Duration `yaml:"timeout" toml:"timeout" env:"SUCTION_QUIC_SERVER_DRAIN_TIMEOUT" envDefault:"10s" validate:"gt=0"`
+	RedirectAddress string        `yaml:"redirect_address" toml:"redirect_address" env:"SUCTION_QUIC_SERVER_DRAIN_REDIRECT_ADDRESS" validate:"omitempty,hostname_port"`
+}
+
+// ConfigPushConfig holds how the server pushes the remote configuration documents in Redis to its
+// clients. A client document replaces the document of the client's group, and clients without a
+// group belong to the default group. Documents are read again every refresh interval and pushed
+// when their version changed.
+type ConfigPushConfig struct {
+	Disabled        bool          `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_SERVER_CONFIG_PUSH_DISABLED"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" toml:"refresh_interval" env:"SUCTION_QUIC_SERVER_CONFIG_PUSH_REFRESH_INTERVAL" envDefault:"30s" validate:"gt=0"`
+	DefaultGroup    string        `yaml:"default_group" toml:"default_group" env:"SUCTION_QUIC_SERVER_CONFIG_PUSH_DEFAULT_GROUP" envDefault:"default" validate:"required"`
+}
+
+// PressureConfig holds when the server asks its clients to send less. The load is how full the
+// fullest pipeline queue is. From the high watermark on, every client is sent a rate below the one
+// it sent at each interval, never below the minimum rate per second, until the load falls to the
+// low watermark and the clients are released.
+type PressureConfig struct {
+	Disabled      bool          `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_SERVER_BACKPRESSURE_DISABLED"`
+	Interval      time.Duration `yaml:"interval" toml:"interval" env:"SUCTION_QUIC_SERVER_BACKPRESSURE_INTERVAL" envDefault:"1s" validate:"gt=0"`
+	HighWatermark float64       `yaml:"high_watermark" toml:"high_watermark" env:"SUCTION_QUIC_SERVER_BACKPRESSURE_HIGH_WATERMARK" envDefault:"0.75" validate:"gt=0,lte=1"`
+	LowWatermark  float64       `yaml:"low_watermark" toml:"low_watermark" env:"SUCTION_QUIC_SERVER_BACKPRESSURE_LOW_WATERMARK" envDefault:"0.25" validate:"gte=0,ltfield=HighWatermark"`
+	MinRate       ByteSize      `yaml:"min_rate" toml:"min_rate" env:"SUCTION_QUIC_SERVER_BACKPRESSURE_MIN_RATE" envDefault:"16KiB" validate:"gt=0"`
+}
+
+// StatusConfig holds how the server keeps the status reports of clients. The latest report of every
+// client is kept in Redis for the TTL. A client whose last report is older than stale_after is
+// reported stale once, checked every check interval, until it reports again.
+type StatusConfig struct {
+	StaleAfter    time.Duration `yaml:"stale_after" toml:"stale_after" env:"SUCTION_QUIC_SERVER_STATUS_STALE_AFTER" envDefault:"2m" validate:"gt=0,ltfield=TTL"`
+	CheckInterval time.Duration `yaml:"check_interval" toml:"check_interval" env:"SUCTION_QUIC_SERVER_STATUS_CHECK_INTERVAL" envDefault:"30s" validate:"gt=0"`
+	TTL           time.Duration `yaml:"ttl" toml:"ttl" env:"SUCTION_QUIC_SERVER_STATUS_TTL" envDefault:"168h" validate:"gt=0"`
+}
+
+// ServerTLSConfig holds the server certificate settings
+type ServerTLSConfig struct {
+	CertFile      string        `yaml:"cert_file" toml:"cert_file" env:"SUCTION_QUIC_SERVER_TLS_CERT_FILE"`
+	KeyFile       string        `yaml:"key_file" toml:"key_file" env:"SUCTION_QUIC_SERVER_TLS_KEY_FILE" validate:"required_with=CertFile"`
+	ExpiryWarning time.Duration `yaml:"expiry_warning" toml:"expiry_warning" env:"SUCTION_QUIC_SERVER_TLS_EXPIRY_WARNING" envDefault:"720h" validate:"gt=0"`
+	ClientCAFile  string        `yaml:"client_ca_file" toml:"client_ca_file" env:"SUCTION_QUIC_SERVER_TLS_CLIENT_CA_FILE"`
+	ClientIDFrom  string        `yaml:"client_id_from" toml:"client_id_from" env:"SUCTION_QUIC_SERVER_TLS_CLIENT_ID_FROM" envDefault:"cn" validate:"oneof=cn san"`
+}
+
+// ClientConfig holds suction-client settings, tuned independently of the server
+type ClientConfig struct {
+	ConnectionAddress              string           `yaml:"connection_address" toml:"connection_address" env:"SUCTION_QUIC_CLIENT_CONNECTION_ADDRESS" envDefault:"localhost:10002" validate:"required,hostname_port"`
+	MaxIdleTimeout                 time.Duration    `yaml:"max_idle_timeout" toml:"max_idle_timeout" env:"SUCTION_QUIC_CLIENT_MAX_IDLE_TIMEOUT" envDefault:"30s" validate:"gt=0"`
+	KeepAlivePeriod                time.Duration    `yaml:"keep_alive_period" toml:"keep_alive_period" env:"SUCTION_QUIC_CLIENT_KEEP_ALIVE_PERIOD" envDefault:"15s" validate:"gt=0,ltfield=MaxIdleTimeout"`
+	MaxIncomingStreams             int64            `yaml:"max_incoming_streams" toml:"max_incoming_streams" env:"SUCTION_QUIC_CLIENT_MAX_INCOMING_STREAMS" envDefault:"16" validate:"gte=0"`
+	MaxIncomingUniStreams          int64            `yaml:"max_incoming_uni_streams" toml:"max_incoming_uni_streams" env:"SUCTION_QUIC_CLIENT_MAX_INCOMING_UNI_STREAMS" envDefault:"16" validate:"gte=0"`
+	InitialStreamReceiveWindow     ByteSize         `yaml:"initial_stream_receive_window" toml:"initial_stream_receive_window" env:"SUCTION_QUIC_CLIENT_INITIAL_STREAM_RECEIVE_WINDOW" envDefault:"512KiB" validate:"gt=0,ltefield=MaxStreamReceiveWindow"`
+	MaxStreamReceiveWindow         ByteSize         `yaml:"max_stream_receive_window" toml:"max_stream_receive_window" env:"SUCTION_QUIC_CLIENT_MAX_STREAM_RECEIVE_WINDOW" envDefault:"2MiB" validate:"gt=0"`
+	InitialConnectionReceiveWindow ByteSize         `yaml:"initial_connection_receive_window" toml:"initial_connection_receive_window" env:"SUCTION_QUIC_CLIENT_INITIAL_CONNECTION_RECEIVE_WINDOW" envDefault:"1MiB" validate:"gt=0,ltefield=MaxConnectionReceiveWindow"`
+	MaxConnectionReceiveWindow     ByteSize         `yaml:"max_connection_receive_window" toml:"max_connection_receive_window" env:"SUCTION_QUIC_CLIENT_MAX_CONNECTION_RECEIVE_WINDOW" envDefault:"4MiB" validate:"gt=0,gtefield=MaxStreamReceiveWindow"`
+	FlushInterval                  time.Duration    `yaml:"flush_interval" toml:"flush_interval" env:"SUCTION_QUIC_CLIENT_FLUSH_INTERVAL" envDefault:"1s" validate:"gt=0" reload:"true"`
+	BackoffInitialInterval         time.Duration    `yaml:"backoff_initial_interval" toml:"backoff_initial_interval" env:"SUCTION_QUIC_CLIENT_BACKOFF_INITIAL_INTERVAL" envDefault:"5s" validate:"gt=0,ltefield=BackoffMaxInterval"`
+	BackoffMaxInterval             time.Duration    `yaml:"backoff_max_interval" toml:"backoff_max_interval" env:"SUCTION_QUIC_CLIENT_BACKOFF_MAX_INTERVAL" envDefault:"60s" validate:"gt=0"`
+	PoolMaxItems                   int              `yaml:"pool_max_items" toml:"pool_max_items" env:"SUCTION_QUIC_CLIENT_POOL_MAX_ITEMS" envDefault:"10000" validate:"gt=0" reload:"true"`
+	MetricsListeningAddress        string           `yaml:"metrics_listening_address" toml:"metrics_listening_address" env:"SUCTION_QUIC_CLIENT_METRICS_LISTENING_ADDRESS" validate:"omitempty,hostname_port"`
+	Auth                           ClientAuthConfig `yaml:"auth" toml:"auth"`
+	TLS                            ClientTLSConfig  `yaml:"tls" toml:"tls"`
+	Resumption                     ClientResumption `yaml:"resumption" toml:"resumption"`
+	Endpoints                      ClientEndpoints  `yaml:"endpoints" toml:"endpoints"`
+	Shutdown                       ClientShutdown   `yaml:"shutdown" toml:"shutdown"`
+	ConfigPush                     ClientConfigPush `yaml:"config_push" toml:"config_push"`
+	Heartbeat                      ClientHeartbeat  `yaml:"heartbeat" toml:"heartbeat"`
+}
+
+// ClientAuthConfig holds the credential sent in the authentication handshake.
+// Either an API key or a signed token may be configured.
+type ClientAuthConfig struct {
+	APIKey  string        `yaml:"api_key" toml:"api_key" env:"SUCTION_QUIC_CLIENT_AUTH_API_KEY" validate:"excluded_with=Token" secret:"true"`
+	Token   string        `yaml:"token" toml:"token" env:"SUCTION_QUIC_CLIENT_AUTH_TOKEN" secret:"true"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"SUCTION_QUIC_CLIENT_AUTH_TIMEOUT" envDefault:"5s" validate:"gt=0"`
+}
+
+// ClientResumption holds the session resumption and 0-RTT settings of the client, both on by default.
+// Session tickets are kept in the cache file so a restarted client resumes without a full handshake;
+// without a file they are only kept in memory. With 0-RTT the client sends its first frames together
+// with the handshake.
+type ClientResumption struct {
+	Disabled         bool   `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_CLIENT_RESUMPTION_DISABLED"`
+	Disable0RTT      bool   `yaml:"disable_0rtt" toml:"disable_0rtt" env:"SUCTION_QUIC_CLIENT_RESUMPTION_DISABLE_0RTT"`
+	SessionCacheFile string `yaml:"session_cache_file" toml:"session_cache_file" env:"SUCTION_QUIC_CLIENT_RESUMPTION_SESSION_CACHE_FILE"`
+	SessionCacheSize int    `yaml:"session_cache_size" toml:"session_cache_size" env:"SUCTION_QUIC_CLIENT_RESUMPTION_SESSION_CACHE_SIZE" envDefault:"16" validate:"gt=0"`
+}
+
+// ClientEndpoints holds the servers the client fails over between. Addresses replace the connection
+// address and are ranked in the listed order; an SRV name replaces both and is resolved again every
+// refresh interval, through the resolver when one is set. An endpoint failing the threshold of
+// consecutive connection attempts is skipped for the ejection duration. A sticky client reconnects
+// to the endpoint it was last connected to before following the strategy.
+type ClientEndpoints struct {
+	Addresses        []string      `yaml:"addresses" toml:"addresses" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_ADDRESSES" validate:"dive,hostname_port"`
+	SRV              string        `yaml:"srv" toml:"srv" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_SRV"`
+	Resolver         string        `yaml:"resolver" toml:"resolver" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_RESOLVER" validate:"omitempty,hostname_port"`
+	RefreshInterval  time.Duration `yaml:"refresh_interval" toml:"refresh_interval" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_REFRESH_INTERVAL" envDefault:"1m" validate:"gt=0"`
+	Strategy         string        `yaml:"strategy" toml:"strategy" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_STRATEGY" envDefault:"priority" validate:"oneof=priority round_robin lowest_rtt"`
+	FailureThreshold int           `yaml:"failure_threshold" toml:"failure_threshold" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_FAILURE_THRESHOLD" envDefault:"3" validate:"gt=0"`
+	EjectionDuration time.Duration `yaml:"ejection_duration" toml:"ejection_duration" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_EJECTION_DURATION" envDefault:"30s" validate:"gt=0"`
+	DisableSticky    bool          `yaml:"disable_sticky" toml:"disable_sticky" env:"SUCTION_QUIC_CLIENT_ENDPOINTS_DISABLE_STICKY"`
+}
+
+// ClientShutdown holds how long a stopping client waits for the server to acknowledge the data
+// still in its pool. What is left unsent is written to the spool file and sent after the next start;
+// without a file it is lost.
+type ClientShutdown struct {
+	Timeout   time.Duration `yaml:"timeout" toml:"timeout" env:"SUCTION_QUIC_CLIENT_SHUTDOWN_TIMEOUT" envDefault:"5s" validate:"gt=0"`
+	SpoolFile string        `yaml:"spool_file" toml:"spool_file" env:"SUCTION_QUIC_CLIENT_SHUTDOWN_SPOOL_FILE"`
+}
+
+// ClientConfigPush holds whether the client applies the configuration pushed by the server. The
+// last applied configuration is kept in the cache file and applies from the start after a restart;
+// without a file it is only kept in memory.
+type ClientConfigPush struct {
+	Disabled  bool   `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_CLIENT_CONFIG_PUSH_DISABLED"`
+	CacheFile string `yaml:"cache_file" toml:"cache_file" env:"SUCTION_QUIC_CLIENT_CONFIG_PUSH_CACHE_FILE"`
+}
+
+// ClientHeartbeat holds the status reports the client sends the server every interval, with the
+// disk usage of the file system holding disk_path
+type ClientHeartbeat struct {
+	Disabled bool          `yaml:"disabled" toml:"disabled" env:"SUCTION_QUIC_CLIENT_HEARTBEAT_DISABLED"`
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"SUCTION_QUIC_CLIENT_HEARTBEAT_INTERVAL" envDefault:"30s" validate:"gt=0"`
+	DiskPath string        `yaml:"disk_path" toml:"disk_path" env:"SUCTION_QUIC_CLIENT_HEARTBEAT_DISK_PATH" envDefault:"/" validate:"required"`
+}
+
+// ClientTLSConfig holds the server verification settings of the client
+type ClientTLSConfig struct {
+	CAFile             string        `yaml:"ca_file" toml:"ca_file" env:"SUCTION_QUIC_CLIENT_TLS_CA_FILE"`
+	CertFile           string        `yaml:"cert_file" toml:"cert_file" env:"SUCTION_QUIC_CLIENT_TLS_CERT_FILE"`
+	KeyFile            string        `yaml:"key_file" toml:"key_file" env:"SUCTION_QUIC_CLIENT_TLS_KEY_FILE" validate:"required_with=CertFile"`
+	ExpiryWarning      time.Duration `yaml:"expiry_warning" toml:"expiry_warning" env:"SUCTION_QUIC_CLIENT_TLS_EXPIRY_WARNING" envDefault:"720h" validate:"gt=0"`
+	ServerName         string        `yaml:"server_name" toml:"server_name" env:"SUCTION_QUIC_CLIENT_TLS_SERVER_NAME"`
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify" toml:"insecure_skip_verify" env:"SUCTION_QUIC_CLIENT_TLS_INSECURE_SKIP_VERIFY"`
+}
+
+// RedisConfig holds Redis connection settings
+type RedisConfig struct {
+	URL                string `yaml:"url" toml:"url" env:"REDIS_URI" validate:"required,url" secret:"true"`
+	RecordStream       string `yaml:"record_stream" toml:"record_stream" env:"REDIS_RECORD_STREAM" envDefault:"suction:records" validate:"required"`
+	RecordStreamMaxLen int64  `yaml:"record_stream_max_len" toml:"record_stream_max_len" env:"REDIS_RECORD_STREAM_MAX_LEN" envDefault:"1000000" validate:"gt=0"`
+	DeadLetterStream   string `yaml:"dead_letter_stream" toml:"dead_letter_stream" env:"REDIS_DEAD_LETTER_STREAM" envDefault:"suction:records:dead-letters" validate:"required"`
+	DeadLetterMaxLen   int64  `yaml:"dead_letter_max_len" toml:"dead_letter_max_len" env:"REDIS_DEAD_LETTER_MAX_LEN" envDefault:"100000" validate:"gt=0"`
+	AuthAPIKeysKey     string `yaml:"auth_api_keys_key" toml:"auth_api_keys_key" env:"REDIS_AUTH_API_KEYS_KEY" envDefault:"suction:auth:api-keys" validate:"required"`
+	AuthSigningKeysKey string `yaml:"auth_signing_keys_key" toml:"auth_signing_keys_key" env:"REDIS_AUTH_SIGNING_KEYS_KEY" envDefault:"suction:auth:signing-keys" validate:"required"`
+	RateLimitKeyPrefix string `yaml:"rate_limit_key_prefix" toml:"rate_limit_key_prefix" env:"REDIS_RATE_LIMIT_KEY_PREFIX" envDefault:"suction:ratelimit" validate:"required"`
+	SessionKeyPrefix   string `yaml:"session_key_prefix" toml:"session_key_prefix" env:"REDIS_SESSION_KEY_PREFIX" envDefault:"suction:sessions" validate:"required"`
+	SessionChannel     string `yaml:"session_channel" toml:"session_channel" env:"REDIS_SESSION_CHANNEL" envDefault:"suction:sessions:events" validate:"required"`
+	TicketKeysKey      string `yaml:"ticket_keys_key" toml:"ticket_keys_key" env:"REDIS_TICKET_KEYS_KEY" envDefault:"suction:tls:ticket-keys" validate:"required"`
+	ConfigKeyPrefix    string `yaml:"config_key_prefix" toml:"config_key_prefix" env:"REDIS_CONFIG_KEY_PREFIX" envDefault:"suction:config" validate:"required"`
+	StatusKeyPrefix    string `yaml:"status_key_prefix" toml:"status_key_prefix" env:"REDIS_STATUS_KEY_PREFIX" envDefault:"suction:status" validate:"required"`
+	StatusChannel      string `yaml:"status_channel" toml:"status_channel" env:"REDIS_STATUS_CHANNEL" envDefault:"suction:status:events" validate:"required"`
+}
+
+// NewConfig resolves the environment and loads the configuration
+func NewConfig() (*Config, error) {
+	return ReadConfig()
+}
+
+// ReadConfig reads the environment file and the config file, for the first load as for a reload.
+// .env.local is read again every time so its edits are picked up, while variables exported to the
+// process keep precedence over it.
+func ReadConfig() (*Config, error) {
+	environment, err := readEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	return loadConfig(environment[ConfigFileEnv], environment)
+}
+
+// readEnvironment returns the process environment, completed with .env.local in the local
+// environment. The process environment itself is left untouched.
+func readEnvironment() (map[string]string, error) {
+	environment := env.ToMap(os.Environ())
+
+	envStr := environment["ENV"]
 	if envStr == "" {
 		return nil, errors.New("ENV environment variable is required")
 	}
 
-	env := Environment(envStr)
-	if env != Local && env != Production {
+	mode := Environment(envStr)
+	if mode != Local && mode != Production {
 		return nil, errors.New("Invalid ENV value: " + envStr)
 	}
 
-	if env == Local {
-		if err := godotenv.Load("../../.env.local"); err != nil {
+	if mode == Local {
+		local, err := godotenv.Read("../../.env.local")
+		if err != nil {
 			return nil, errors.New("Could not load .env.local file: " + err.Error())
 		}
-	}
 
-	quicMaxIdleTimeout, err := validateAndGetEnv("SUCTION_QUIC_MAX_IDLE_TIMEOUT", "int64")
-	if err != nil {
-		return nil, err
+		for key, value := range local {
+			if _, ok := environment[key]; !ok {
+				environment[key] = value
+			}
+		}
 	}
 
-	quicKeepAlivePeriod, err := validateAndGetEnv("SUCTION_QUIC_KEEP_ALIVE_PERIOD", "int64")
-	if err != nil {
-		return nil, err
-	}
+	return environment, nil
+}
 
-	quicServerListeningAddress, err := validateAndGetEnv("SUCTION_QUIC_SERVER_LISTENING_ADDRESS", "string")
-	if err != nil {
-		return nil, err
-	}
+// LoadConfig builds a Config from defaults, the optional config file and environment variables.
+// Every decoding and validation error is reported at once.
+func LoadConfig(path string) (*Config, error) {
+	return loadConfig(path, env.ToMap(os.Environ()))
+}
 
-	quicServerStreamBufferSize, err := validateAndGetEnv("SUCTION_QUIC_SERVER_STREAM_BUFFER_SIZE", "int")
-	if err != nil {
-		return nil, err
-	}
+func loadConfig(path string, environment map[string]string) (*Config, error) {
+	config := &Config{File: path}
+	var errs []error
 
-	quicServerMaxIncomingStreams, err := validateAndGetEnv("SUCTION_QUIC_SERVER_MAX_INCOMING_STREAMS", "int64")
-	if err != nil {
-		return nil, err
-	}
+	// Defaults are applied before the file is decoded, so a value the file sets, zero included,
+	// is kept. The environment is parsed last without defaults.
+	errs = append(errs, parseEnv(config, env.Options{Environment: map[string]string{}})...)
 
-	quicServerMaxIncomingUniStreams, err := validateAndGetEnv("SUCTION_QUIC_SERVER_MAX_INCOMING_UNI_STREAMS", "int64")
-	if err != nil {
-		return nil, err
+	if path != "" {
+		if err := decodeConfigFile(path, config); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	quicServerInitialStreamReceiveWindow, err := validateAndGetEnv("SUCTION_QUIC_SERVER_INITIAL_STREAM_RECEIVE_WINDOW", "uint64")
-	if err != nil {
-		return nil, err
+	errs = append(errs, parseEnv(config, env.Options{Environment: environment, DefaultValueTagName: noDefaultTagName})...)
+	errs = append(errs, config.Validate()...)
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
-	quicServerMaxStreamReceiveWindow, err := validateAndGetEnv("SUCTION_QUIC_SERVER_MAX_STREAM_RECEIVE_WINDOW", "uint64")
-	if err != nil {
-		return nil, err
+	return config, nil
+}
+
+// noDefaultTagName is a tag no field carries, so parsing with it as the default value tag leaves
+// the fields without a variable untouched
+const noDefaultTagName = "-"
+
+func parseEnv(config *Config, options env.Options) []error {
+	err := env.ParseWithOptions(config, options)
+	if err == nil {
+		return nil
 	}
 
-	quicServerInitialConnectionReceiveWindow, err := validateAndGetEnv("SUCTION_QUIC_SERVER_INITIAL_CONNECTION_RECEIVE_WINDOW", "uint64")
-	if err != nil {
-		return nil, err
+	var aggregateErr env.AggregateError
+	if errors.As(err, &aggregateErr) {
+		return aggregateErr.Errors
 	}
 
-	quicServerMaxConnectionReceiveWindow, err := validateAndGetEnv("SUCTION_QUIC_SERVER_MAX_CONNECTION_RECEIVE_WINDOW", "uint64")
-	if err != nil {
-		return nil, err
+	return []error{err}
+}
+
+// Validate runs the declarative validation rules and returns every violation
+func (c *Config) Validate() []error {
+	err := configValidator.Struct(c)
+	if err == nil {
+		return nil
 	}
 
-	quicClientConnectionAddress, err := validateAndGetEnv("SUCTION_QUIC_CLIENT_CONNECTION_ADDRESS", "string")
-	if err != nil {
-		return nil, err
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []error{err}
 	}
 
-	redisURI, err := validateAndGetEnv("REDIS_URI", "string")
-	if err != nil {
-		return nil, err
+	errs := make([]error, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		errs = append(errs, newValidationError(fieldErr))
 	}
 
-	return &Config{
-		Env:                                      env,
-		QuicMaxIdleTimeout:                       quicMaxIdleTimeout.(int64),
-		QuicKeepAlivePeriod:                      quicKeepAlivePeriod.(int64),
-		QuicServerListeningAddress:               quicServerListeningAddress.(string),
-		QuicServerStreamBufferSize:               quicServerStreamBufferSize.(int),
-		QuicServerMaxIncomingStreams:             quicServerMaxIncomingStreams.(int64),
-		QuicServerMaxIncomingUniStreams:          quicServerMaxIncomingUniStreams.(int64),
-		QuicServerInitialStreamReceiveWindow:     quicServerInitialStreamReceiveWindow.(uint64),
-		QuicServerMaxStreamReceiveWindow:         quicServerMaxStreamReceiveWindow.(uint64),
-		QuicServerInitialConnectionReceiveWindow: quicServerInitialConnectionReceiveWindow.(uint64),
-		QuicServerMaxConnectionReceiveWindow:     quicServerMaxConnectionReceiveWindow.(uint64),
-		QuicClientConnectionAddress:              quicClientConnectionAddress.(string),
-		RedisURL:                                 redisURI.(string),
-	}, nil
+	return errs
 }
 
 func (c *Config) IsLocal() bool {
@@ -135,35 +452,117 @@ func (c *Config) IsProduction() bool {
 	return c.Env == Production
 }
 
-func validateAndGetEnv(key string, valType string) (interface{}, error) {
-	val := os.Getenv(key)
+var configValidator = newConfigValidator()
+
+var comparisonNames = map[string]string{
+	"ltfield":  "less than",
+	"ltefield": "less than or equal to",
+	"gtfield":  "greater than",
+	"gtefield": "greater than or equal to",
+}
+
+func newConfigValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterStructValidation(validateConfig, Config{})
+	v.RegisterTagNameFunc(configKey)
+
+	return v
+}
 
-	if val == "" {
-		return nil, errors.New("\"" + key + "\" is required")
+// configKey names a field by its key in the config file
+func configKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "" || name == "-" {
+		return field.Name
 	}
 
-	switch valType {
-	case "int":
-		intVal, err := strconv.Atoi(val)
-		if err != nil {
-			return nil, errors.New("Invalid " + key + ": " + err.Error())
+	return name
+}
+
+func validateConfig(sl validator.StructLevel) {
+	config := sl.Current().Interface().(Config)
+
+	if config.Env != Local && config.Client.TLS.InsecureSkipVerify {
+		sl.ReportError(config.Client.TLS.InsecureSkipVerify, "client.tls.insecure_skip_verify", "InsecureSkipVerify", "local_only", "")
+	}
+
+	for clientID, limit := range config.Server.RateLimit.Clients {
+		if _, ok := config.Server.RateLimit.Tiers[limit.Tier]; limit.Tier != "" && !ok {
+			sl.ReportError(limit.Tier, "server.rate_limit.clients["+clientID+"].tier", "Tier", "tier_defined", "")
 		}
-		return intVal, nil
-	case "int64":
-		int64Val, err := strconv.ParseInt(val, 10, 64)
-		if err != nil {
-			return nil, errors.New("Invalid " + key + ": " + err.Error())
+	}
+}
+
+func newValidationError(fieldErr validator.FieldError) error {
+	key := strings.TrimPrefix(fieldErr.Namespace(), "Config.")
+
+	switch fieldErr.Tag() {
+	case "required":
+		return errors.New("\"" + key + "\" is required")
+	case "oneof":
+		return fmt.Errorf("Invalid %s: must be one of [%s]", key, fieldErr.Param())
+	case "local_only":
+		return fmt.Errorf("Invalid %s: only allowed in local environment", key)
+	case "tier_defined":
+		return fmt.Errorf("Invalid %s: tier %v is not defined in server.rate_limit.tiers", key, fieldErr.Value())
+	case "ltfield", "ltefield", "gtfield", "gtefield":
+		return fmt.Errorf("Invalid %s: must be %s %s (got %v)", key, comparisonNames[fieldErr.Tag()], siblingKey(fieldErr), fieldErr.Value())
+	default:
+		return fmt.Errorf("Invalid %s: failed %q rule %s (got %v)", key, fieldErr.Tag(), fieldErr.Param(), fieldErr.Value())
+	}
+}
+
+func decodeConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.New("Could not read config file: " + err.Error())
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, config)
+	case ".toml":
+		err = toml.Unmarshal(data, config)
+	default:
+		return errors.New("Unsupported config file format: " + path)
+	}
+
+	if err != nil {
+		return errors.New("Invalid config file " + path + ": " + err.Error())
+	}
+
+	return nil
+}
+
+// siblingKey returns the config key of the field a cross-field rule compares against, which the
+// validator only knows by its Go name
+func siblingKey(fieldErr validator.FieldError) string {
+	names := strings.Split(fieldErr.StructNamespace(), ".")
+	parent := reflect.TypeOf(Config{})
+
+	for _, name := range names[1 : len(names)-1] {
+		name, _, _ = strings.Cut(name, "[")
+
+		field, ok := parent.FieldByName(name)
+		if !ok {
+			return fieldErr.Param()
 		}
-		return int64Val, nil
-	case "uint64":
-		uint64Val, err := strconv.ParseUint(val, 10, 64)
-		if err != nil {
-			return nil, errors.New("Invalid " + key + ": " + err.Error())
+
+		parent = field.Type
+		for parent.Kind() == reflect.Pointer || parent.Kind() == reflect.Map || parent.Kind() == reflect.Slice {
+			parent = parent.Elem()
 		}
-		return uint64Val, nil
-	case "string":
-		return val, nil
-	default:
-		return nil, errors.New("Invalid value type: " + valType)
 	}
+
+	field, ok := parent.FieldByName(fieldErr.Param())
+	if !ok {
+		return fieldErr.Param()
+	}
+
+	key := strings.TrimPrefix(fieldErr.Namespace(), "Config.")
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i+1] + configKey(field)
+	}
+
+	return configKey(field)
 }
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"go/pb"
+)
+
+// ConfigDocument is the JSON document in Redis holding the settings pushed to clients, for example
+// {"version": 3, "flush_interval": "5s", "pool_max_items": 20000}. Settings left out keep the value
+// of the client configuration. A client applies a document once per version.
+type ConfigDocument struct {
+	Version       uint64 `json:"version"`
+	FlushInterval string `json:"flush_interval,omitempty"`
+	PoolMaxItems  uint32 `json:"pool_max_items,omitempty"`
+}
+
+// ClientConfigKey returns the key of the document pushed to a single client
+func ClientConfigKey(prefix, clientID string) string {
+	return prefix + ":clients:" + clientID
+}
+
+// GroupConfigKey returns the key of the document pushed to the clients of a group
+func GroupConfigKey(prefix, group string) string {
+	return prefix + ":groups:" + group
+}
+
+// ClientGroupsKey returns the key of the hash mapping client IDs to their group
+func ClientGroupsKey(prefix string) string {
+	return prefix + ":client-groups"
+}
+
+// ParseConfigDocument decodes a document and converts it to the message pushed to clients
+func ParseConfigDocument(data []byte, source string) (*pb.RemoteConfig, error) {
+	document := ConfigDocument{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, errors.New("Invalid config document " + source + ": " + err.Error())
+	}
+
+	config, err := document.Message()
+	if err != nil {
+		return nil, errors.New("Invalid config document " + source + ": " + err.Error())
+	}
+	config.Source = source
+
+	return config, nil
+}
+
+// Message validates the document and converts it to the message pushed to clients
+func (d ConfigDocument) Message() (*pb.RemoteConfig, error) {
+	if d.Version == 0 {
+		return nil, errors.New("version must be greater than 0")
+	}
+
+	config := &pb.RemoteConfig{
+		Version:      d.Version,
+		PoolMaxItems: d.PoolMaxItems,
+	}
+
+	if d.FlushInterval != "" {
+		interval, err := time.ParseDuration(d.FlushInterval)
+		if err != nil {
+			return nil, errors.New("invalid flush_interval " + strconv.Quote(d.FlushInterval))
+		}
+		if interval < time.Millisecond {
+			return nil, errors.New("flush_interval must be at least 1ms")
+		}
+		config.FlushIntervalMs = uint32(interval.Milliseconds())
+	}
+
+	return config, nil
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/quic-go/quic-go"
+)
+
+// QUIC application error codes used when closing suction connections, resetting streams and
+// cancelling their reads. Stream errors carry the same values as quic.StreamErrorCode.
+const (
+	ErrorCodeNoError           quic.ApplicationErrorCode = 0x0
+	ErrorCodeAuthFailed        quic.ApplicationErrorCode = 0x101
+	ErrorCodeAuthTimeout       quic.ApplicationErrorCode = 0x102
+	ErrorCodeRateLimited       quic.ApplicationErrorCode = 0x103
+	ErrorCodeServerBusy        quic.ApplicationErrorCode = 0x104
+	ErrorCodeAdminClosed       quic.ApplicationErrorCode = 0x105
+	ErrorCodeDraining          quic.ApplicationErrorCode = 0x106
+	ErrorCodeProtocolViolation quic.ApplicationErrorCode = 0x107
+	ErrorCodeFrameTooLarge     quic.ApplicationErrorCode = 0x108
+	ErrorCodeInternal          quic.ApplicationErrorCode = 0x109
+)
+
+// errorCodeNames names the codes in logs
+var errorCodeNames = map[quic.ApplicationErrorCode]string{
+	ErrorCodeNoError:           "no_error",
+	ErrorCodeAuthFailed:        "auth_failed",
+	ErrorCodeAuthTimeout:       "auth_timeout",
+	ErrorCodeRateLimited:       "throttled",
+	ErrorCodeServerBusy:        "server_busy",
+	ErrorCodeAdminClosed:       "admin_closed",
+	ErrorCodeDraining:          "draining",
+	ErrorCodeProtocolViolation: "protocol_violation",
+	ErrorCodeFrameTooLarge:     "frame_too_large",
+	ErrorCodeInternal:          "internal_error",
+}
+
+// ErrorCodeName returns the name of a code, or its hex value when it is not a suction code
+func ErrorCodeName(code quic.ApplicationErrorCode) string {
+	if name, ok := errorCodeNames[code]; ok {
+		return name
+	}
+
+	return "0x" + strconv.FormatUint(uint64(code), 16)
+}
+
+// ErrorCodeRetryable reports whether reconnecting can succeed after the peer used the code.
+// A rejected credential is rejected again until the configuration changes.
+func ErrorCodeRetryable(code quic.ApplicationErrorCode) bool {
+	return code != ErrorCodeAuthFailed
+}
+
+// ErrorCodeFromError returns the code a connection or stream was closed with by the peer
+func ErrorCodeFromError(err error) (quic.ApplicationErrorCode, bool) {
+	var appErr *quic.ApplicationError
+	if errors.As(err, &appErr) && appErr.Remote {
+		return appErr.ErrorCode, true
+	}
+
+	var streamErr *quic.StreamError
+	if errors.As(err, &streamErr) && streamErr.Remote {
+		return quic.ApplicationErrorCode(streamErr.ErrorCode), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,225 @@
+package common
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go/pb"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/proto"
+)
+
+// FrameType identifies the message carried by a frame
+type FrameType uint8
+
+const (
+	FrameClientData FrameType = iota + 1
+	FrameAuthRequest
+	FrameAuthResponse
+	FrameAck
+	FrameDrain
+	FrameError
+	FrameControl
+	FrameConfig
+	FrameConfigAck
+	FrameBackpressure
+	FrameClientStatus
+)
+
+// FrameHeaderSize is the size of the type byte and the big-endian payload length preceding every payload
+const FrameHeaderSize = 5
+
+// ControlFrameMaxSize bounds the payload of frames sent by the server to clients
+const ControlFrameMaxSize = 64 * 1024
+
+// ErrFrameTooLarge is returned when a peer announces a payload above the configured limit
+var ErrFrameTooLarge = errors.New("frame too large")
+
+var frameTypeNames = map[FrameType]string{
+	FrameClientData:   "client_data",
+	FrameAuthRequest:  "auth_request",
+	FrameAuthResponse: "auth_response",
+	FrameAck:          "ack",
+	FrameDrain:        "drain",
+	FrameError:        "error",
+	FrameControl:      "control",
+	FrameConfig:       "config",
+	FrameConfigAck:    "config_ack",
+	FrameBackpressure: "backpressure",
+	FrameClientStatus: "client_status",
+}
+
+func (t FrameType) String() string {
+	if name, ok := frameTypeNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown(%d)", uint8(t))
+}
+
+// Frame is a single length-prefixed message read from a stream
+type Frame struct {
+	Type    FrameType
+	Payload []byte
+	buffer  *[]byte
+}
+
+// Release returns the payload buffer to the pool. The payload must not be used afterwards.
+func (f *Frame) Release() {
+	Buffers.Put(f.buffer)
+	f.buffer = nil
+	f.Payload = nil
+}
+
+// FrameReader reads frames from a buffered stream
+type FrameReader struct {
+	reader  *bufio.Reader
+	maxSize int
+	header  [FrameHeaderSize]byte
+}
+
+// NewFrameReader creates a reader buffering bufferSize bytes and rejecting payloads above maxSize
+func NewFrameReader(r io.Reader, bufferSize, maxSize int) *FrameReader {
+	return &FrameReader{
+		reader:  bufio.NewReaderSize(r, bufferSize),
+		maxSize: maxSize,
+	}
+}
+
+// ReadFrame reads the next frame into a pooled buffer. io.EOF is returned only on a clean frame boundary.
+// Callers done with the payload may Release the frame; frames that are not released are garbage collected.
+func (fr *FrameReader) ReadFrame() (*Frame, error) {
+	if _, err := io.ReadFull(fr.reader, fr.header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(fr.header[1:])
+	if int64(size) > int64(fr.maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes exceeds %d", ErrFrameTooLarge, size, fr.maxSize)
+	}
+
+	buffer := Buffers.Get(int(size))
+	if _, err := io.ReadFull(fr.reader, *buffer); err != nil {
+		Buffers.Put(buffer)
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return &Frame{Type: FrameType(fr.header[0]), Payload: *buffer, buffer: buffer}, nil
+}
+
+// WriteFrame writes a frame with a single Write call so concurrent writers never interleave partial frames
+func WriteFrame(w io.Writer, frameType FrameType, payload []byte) error {
+	buffer := Buffers.Get(FrameHeaderSize + len(payload))
+	defer Buffers.Put(buffer)
+
+	frame := *buffer
+	frame[0] = byte(frameType)
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	copy(frame[FrameHeaderSize:], payload)
+
+	_, err := w.Write(frame)
+
+	return err
+}
+
+// WriteMessage marshals a protobuf message and writes it as a frame
+func WriteMessage(w io.Writer, frameType FrameType, message proto.Message) error {
+	payload, err := proto.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return WriteFrame(w, frameType, payload)
+}
+
+// ReadMessage reads the next frame, checks its type and unmarshals it into message
+func ReadMessage(fr *FrameReader, frameType FrameType, message proto.Message) error {
+	frame, err := fr.ReadFrame()
+	if err != nil {
+		return err
+	}
+	defer frame.Release()
+
+	if frame.Type != frameType {
+		return fmt.Errorf("unexpected frame %s, expected %s", frame.Type, frameType)
+	}
+
+	return proto.Unmarshal(frame.Payload, message)
+}
+
+// EncodeClientData marshals and snappy-compresses a data message, returning the frame payload and the raw size
+func EncodeClientData(data *pb.ClientData) ([]byte, int, error) {
+	protobufData, err := proto.Marshal(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return snappy.Encode(nil, protobufData), len(protobufData), nil
+}
+
+// DecodeClientData decompresses and unmarshals a data frame payload, returning the message and its raw protobuf bytes
+func DecodeClientData(payload []byte) (*pb.ClientData, []byte, error) {
+	protobufData, err := snappy.Decode(nil, payload)
+	if err != nil {
+		return nil, nil, errors.New("Failed to decompress snappy data: " + err.Error())
+	}
+
+	data := &pb.ClientData{}
+	if err := proto.Unmarshal(protobufData, data); err != nil {
+		return nil, nil, errors.New("Failed to unmarshal protobuf message: " + err.Error())
+	}
+
+	return data, protobufData, nil
+}
+
+// EncodeClientDataPooled is EncodeClientData using pooled buffers. The returned payload must be
+// given back with Buffers.Put once the frame is written.
+func EncodeClientDataPooled(data *pb.ClientData) (*[]byte, int, error) {
+	protobufData := Buffers.Get(proto.Size(data))
+	defer Buffers.Put(protobufData)
+
+	marshaled, err := proto.MarshalOptions{}.MarshalAppend((*protobufData)[:0], data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payload := Buffers.Get(snappy.MaxEncodedLen(len(marshaled)))
+	*payload = snappy.Encode(*payload, marshaled)
+
+	return payload, len(marshaled), nil
+}
+
+// DecodeClientDataPooled is DecodeClientData using a pooled message and buffer. Payloads that
+// decompress to more than maxSize bytes are rejected with ErrFrameTooLarge before any buffer is taken.
+// The message must be given back with ReleaseClientData and the raw protobuf bytes with Buffers.Put once unused.
+func DecodeClientDataPooled(payload []byte, maxSize int) (*pb.ClientData, *[]byte, error) {
+	size, err := snappy.DecodedLen(payload)
+	if err != nil {
+		return nil, nil, errors.New("Failed to decompress snappy data: " + err.Error())
+	}
+	if size > maxSize {
+		return nil, nil, fmt.Errorf("%w: decompresses to %d bytes, above %d", ErrFrameTooLarge, size, maxSize)
+	}
+
+	protobufData := Buffers.Get(size)
+	if *protobufData, err = snappy.Decode(*protobufData, payload); err != nil {
+		Buffers.Put(protobufData)
+		return nil, nil, errors.New("Failed to decompress snappy data: " + err.Error())
+	}
+
+	data := AcquireClientData()
+	if err := (proto.UnmarshalOptions{Merge: true}).Unmarshal(*protobufData, data); err != nil {
+		ReleaseClientData(data)
+		Buffers.Put(protobufData)
+		return nil, nil, errors.New("Failed to unmarshal protobuf message: " + err.Error())
+	}
+
+	return data, protobufData, nil
+}
@@ -0,0 +1,353 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"go/pb"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestData(readings int) *pb.ClientData {
+	data := &pb.ClientData{
+		Sequence:       1,
+		Timestamp:      time.Now().Unix(),
+		Message:        "frame test",
+		SensorReadings: make([]float32, readings),
+	}
+	for i := range data.SensorReadings {
+		data.SensorReadings[i] = rand.Float32() * 100.0
+	}
+
+	return data
+}
+
+func TestClientDataPooledRoundTrip(t *testing.T) {
+	for _, readings := range []int{0, 10, 1000, 100000} {
+		data := newTestData(readings)
+
+		payload, size, err := EncodeClientDataPooled(data)
+		if err != nil {
+			t.Fatalf("%d readings: encode: %v", readings, err)
+		}
+		if size != proto.Size(data) {
+			t.Errorf("%d readings: encoded size %d, want %d", readings, size, proto.Size(data))
+		}
+
+		plain, _, err := EncodeClientData(data)
+		if err != nil {
+			t.Fatalf("%d readings: plain encode: %v", readings, err)
+		}
+		if !bytes.Equal(*payload, plain) {
+			t.Errorf("%d readings: pooled payload differs from the plain one", readings)
+		}
+
+		stream := &bytes.Buffer{}
+		if err := WriteFrame(stream, FrameClientData, *payload); err != nil {
+			t.Fatalf("%d readings: write frame: %v", readings, err)
+		}
+		Buffers.Put(payload)
+
+		frame, err := NewFrameReader(stream, FrameHeaderSize, len(plain)).ReadFrame()
+		if err != nil {
+			t.Fatalf("%d readings: read frame: %v", readings, err)
+		}
+		if frame.Type != FrameClientData {
+			t.Errorf("%d readings: frame type %s, want %s", readings, frame.Type, FrameClientData)
+		}
+
+		decoded, raw, err := DecodeClientDataPooled(frame.Payload, testMaxDecodedSize)
+		frame.Release()
+		if err != nil {
+			t.Fatalf("%d readings: decode: %v", readings, err)
+		}
+		if !proto.Equal(decoded, data) {
+			t.Errorf("%d readings: decoded message differs from the original", readings)
+		}
+
+		ReleaseClientData(decoded)
+		Buffers.Put(raw)
+	}
+}
+
+func TestDecodeIntoReleasedMessage(t *testing.T) {
+	large, _, err := EncodeClientData(newTestData(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	small := newTestData(3)
+	small.Message = ""
+	payload, _, err := EncodeClientData(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A message released after a larger one must not carry its readings or fields into the next decode
+	for range 100 {
+		decoded, raw, err := DecodeClientDataPooled(large, testMaxDecodedSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ReleaseClientData(decoded)
+		Buffers.Put(raw)
+
+		decoded, raw, err = DecodeClientDataPooled(payload, testMaxDecodedSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(decoded, small) {
+			t.Fatalf("decoded %d readings and message %q, want %d readings and no message",
+				len(decoded.SensorReadings), decoded.Message, len(small.SensorReadings))
+		}
+		ReleaseClientData(decoded)
+		Buffers.Put(raw)
+	}
+}
+
+func TestHeldFrameKeepsItsBuffer(t *testing.T) {
+	stream := &bytes.Buffer{}
+	for i := range 3 {
+		if err := WriteFrame(stream, FrameClientData, bytes.Repeat([]byte{byte('a' + i)}, 700)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reader := NewFrameReader(stream, FrameHeaderSize, 1024)
+
+	held, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bytes.Clone(held.Payload)
+
+	// The next frames come from the same size class, and a released buffer is the first one Get
+	// hands out again. None of them may land in the buffer the held frame still reads.
+	next, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.Release()
+
+	last, err := reader.ReadFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer last.Release()
+
+	if !bytes.Equal(held.Payload, want) {
+		t.Fatalf("held payload was overwritten by a later frame")
+	}
+	if &held.Payload[0] == &last.Payload[0] {
+		t.Fatalf("a later frame reuses the buffer of the held frame")
+	}
+
+	held.Release()
+	if held.Payload != nil {
+		t.Errorf("released frame still exposes its payload")
+	}
+	held.Release()
+}
+
+func TestReadFrameTooLarge(t *testing.T) {
+	stream := &bytes.Buffer{}
+	if err := WriteFrame(stream, FrameClientData, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewFrameReader(stream, FrameHeaderSize, 99).ReadFrame(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	payload, size, err := EncodeClientData(newTestData(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := DecodeClientDataPooled(payload, size-1); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	decoded, raw, err := DecodeClientDataPooled(payload, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ReleaseClientData(decoded)
+	Buffers.Put(raw)
+}
+
+// testMaxDecodedSize is the decoded size limit of the tests that do not exercise it
+const testMaxDecodedSize = 64 << 20
+
+// benchReadings is the size of the messages the benchmarks encode and decode
+const benchReadings = 1000
+
+// reportGC reports the garbage collections per ten thousand operations, the GC pressure at that
+// message rate
+func reportGC(b *testing.B, run func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	b.ReportAllocs()
+	run()
+
+	runtime.ReadMemStats(&after)
+	b.ReportMetric(float64(after.NumGC-before.NumGC)/float64(b.N)*10000, "gc/10kops")
+}
+
+func BenchmarkEncode(b *testing.B) {
+	data := newTestData(benchReadings)
+
+	b.Run("plain", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				if _, _, err := EncodeClientData(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				payload, _, err := EncodeClientDataPooled(data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				Buffers.Put(payload)
+			}
+		})
+	})
+}
+
+func BenchmarkDecode(b *testing.B) {
+	payload, _, err := EncodeClientData(newTestData(benchReadings))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("plain", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				if _, _, err := DecodeClientData(payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				decoded, raw, err := DecodeClientDataPooled(payload, testMaxDecodedSize)
+				if err != nil {
+					b.Fatal(err)
+				}
+				ReleaseClientData(decoded)
+				Buffers.Put(raw)
+			}
+		})
+	})
+}
+
+func BenchmarkReadFrame(b *testing.B) {
+	frame := benchFrame(b)
+
+	b.Run("plain", func(b *testing.B) {
+		reader := NewFrameReader(&repeatReader{data: frame}, 8*1024, len(frame))
+		reportGC(b, func() {
+			for b.Loop() {
+				if _, err := reader.ReadFrame(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		reader := NewFrameReader(&repeatReader{data: frame}, 8*1024, len(frame))
+		reportGC(b, func() {
+			for b.Loop() {
+				frame, err := reader.ReadFrame()
+				if err != nil {
+					b.Fatal(err)
+				}
+				frame.Release()
+			}
+		})
+	})
+}
+
+// BenchmarkIngest reads and decodes frames the way the server pipeline does
+func BenchmarkIngest(b *testing.B) {
+	frame := benchFrame(b)
+
+	b.Run("plain", func(b *testing.B) {
+		reader := NewFrameReader(&repeatReader{data: frame}, 8*1024, len(frame))
+		reportGC(b, func() {
+			for b.Loop() {
+				frame, err := reader.ReadFrame()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, _, err := DecodeClientData(frame.Payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		reader := NewFrameReader(&repeatReader{data: frame}, 8*1024, len(frame))
+		reportGC(b, func() {
+			for b.Loop() {
+				frame, err := reader.ReadFrame()
+				if err != nil {
+					b.Fatal(err)
+				}
+				decoded, raw, err := DecodeClientDataPooled(frame.Payload, testMaxDecodedSize)
+				if err != nil {
+					b.Fatal(err)
+				}
+				frame.Release()
+				ReleaseClientData(decoded)
+				Buffers.Put(raw)
+			}
+		})
+	})
+}
+
+// benchFrame returns a data frame holding a message of benchReadings readings
+func benchFrame(b *testing.B) []byte {
+	payload, _, err := EncodeClientData(newTestData(benchReadings))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	frame := &bytes.Buffer{}
+	if err := WriteFrame(frame, FrameClientData, payload); err != nil {
+		b.Fatal(err)
+	}
+
+	return frame.Bytes()
+}
+
+// repeatReader serves the same bytes forever, so a frame reader sees an endless stream of one frame
+type repeatReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		copied := copy(p[n:], r.data[r.offset:])
+		n += copied
+		r.offset = (r.offset + copied) % len(r.data)
+	}
+
+	return n, nil
+}
@@ -5,9 +5,18 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-func NewLogger() (*zap.Logger, error) {
-	config := zap.NewDevelopmentConfig()
-	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+// NewLogger builds the application logger with a level that can be changed at runtime
+func NewLogger(config *Config) (*zap.Logger, zap.AtomicLevel, error) {
+	level, err := zap.ParseAtomicLevel(config.Log.Level)
+	if err != nil {
+		return nil, level, err
+	}
 
-	return config.Build()
+	loggerConfig := zap.NewDevelopmentConfig()
+	loggerConfig.Level = level
+	loggerConfig.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+
+	logger, err := loggerConfig.Build()
+
+	return logger, level, err
 }
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const metricsNamespace = "suction"
+
+// Metrics holds the Prometheus registry shared by application components
+type Metrics struct {
+	Registry *prometheus.Registry
+}
+
+// NewMetrics creates a registry with the Go runtime and process collectors
+func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return &Metrics{Registry: registry}
+}
+
+// Serve exposes the registry on /metrics at address with the application lifecycle.
+// An empty address disables the endpoint.
+func (m *Metrics) Serve(lifecycle fx.Lifecycle, logger *zap.Logger, address string) {
+	if address == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry}))
+
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return errors.New("Failed to start metrics server: " + err.Error())
+			}
+
+			logger.Info("Starting metrics server", zap.String("address", address))
+
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					logger.Error("Metrics server stopped", zap.Error(err))
+				}
+			}()
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			logger.Info("Stopping metrics server")
+
+			return server.Shutdown(ctx)
+		},
+	})
+}
@@ -5,5 +5,6 @@ import (
 )
 
 var Module = fx.Options(
-	fx.Provide(NewConfig, NewLogger, NewTLSConfig),
+	fx.Provide(NewConfig, NewLogger, NewMetrics, NewServerTLSConfig, NewClientTLSConfig, NewConfigReloader),
+	fx.Invoke(func(*ConfigReloader) {}),
 )
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"math/bits"
+	"sync"
+
+	"go/pb"
+)
+
+const (
+	bufferPoolMinSize = 512
+	bufferPoolMaxSize = 16 * 1024 * 1024
+)
+
+// Buffers is the buffer pool shared by the frame and codec hot paths of the client and server
+var Buffers = NewBufferPool(bufferPoolMinSize, bufferPoolMaxSize)
+
+// BufferPool recycles byte slices in power-of-two size classes, so a small frame never pins a
+// large buffer. Requests above the largest class are allocated and dropped on Put.
+type BufferPool struct {
+	minShift int
+	classes  []sync.Pool
+}
+
+// NewBufferPool creates a pool with classes from minSize up to maxSize, both rounded up to a power of two
+func NewBufferPool(minSize, maxSize int) *BufferPool {
+	minShift := bits.Len(uint(minSize - 1))
+	maxShift := bits.Len(uint(maxSize - 1))
+
+	pool := &BufferPool{
+		minShift: minShift,
+		classes:  make([]sync.Pool, maxShift-minShift+1),
+	}
+
+	for i := range pool.classes {
+		capacity := 1 << (minShift + i)
+		pool.classes[i].New = func() any {
+			buffer := make([]byte, 0, capacity)
+			return &buffer
+		}
+	}
+
+	return pool
+}
+
+// Get returns a buffer of length size. Its content is undefined.
+func (p *BufferPool) Get(size int) *[]byte {
+	class := p.classOf(size)
+	if class >= len(p.classes) {
+		buffer := make([]byte, size)
+		return &buffer
+	}
+
+	buffer := p.classes[class].Get().(*[]byte)
+	*buffer = (*buffer)[:size]
+
+	return buffer
+}
+
+// Put returns a buffer obtained from Get. The buffer must not be used afterwards.
+func (p *BufferPool) Put(buffer *[]byte) {
+	if buffer == nil {
+		return
+	}
+
+	// A buffer only goes back to the class it fully covers, so Get never returns a short one
+	capacity := cap(*buffer)
+	class := p.classOf(capacity)
+	if 1<<(p.minShift+class) != capacity || class >= len(p.classes) {
+		return
+	}
+
+	*buffer = (*buffer)[:0]
+	p.classes[class].Put(buffer)
+}
+
+func (p *BufferPool) classOf(size int) int {
+	if size <= 1<<p.minShift {
+		return 0
+	}
+
+	return bits.Len(uint(size-1)) - p.minShift
+}
+
+var clientDataPool = sync.Pool{
+	New: func() any {
+		return &pb.ClientData{}
+	},
+}
+
+// AcquireClientData returns an empty message from the pool. Its readings keep the capacity of
+// earlier use, so appending into it rarely allocates. Unmarshaling still allocates the readings.
+func AcquireClientData() *pb.ClientData {
+	return clientDataPool.Get().(*pb.ClientData)
+}
+
+// ReleaseClientData resets a message and returns it to the pool. The message must not be used afterwards.
+func ReleaseClientData(data *pb.ClientData) {
+	if data == nil {
+		return
+	}
+
+	readings := data.SensorReadings[:0]
+	data.Reset()
+	data.SensorReadings = readings
+
+	clientDataPool.Put(data)
+}
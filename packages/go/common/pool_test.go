@@ -0,0 +1,138 @@
+package common
+
+import (
+	"testing"
+
+	"go/pb"
+)
+
+func TestBufferPoolSizeClasses(t *testing.T) {
+	pool := NewBufferPool(500, 5000)
+
+	tests := []struct {
+		size     int
+		capacity int
+	}{
+		{0, 512},
+		{1, 512},
+		{512, 512},
+		{513, 1024},
+		{1024, 1024},
+		{4097, 8192},
+		{8192, 8192},
+		{8193, 8193},
+	}
+
+	for _, test := range tests {
+		buffer := pool.Get(test.size)
+		if len(*buffer) != test.size {
+			t.Errorf("Get(%d): length %d", test.size, len(*buffer))
+		}
+		if cap(*buffer) != test.capacity {
+			t.Errorf("Get(%d): capacity %d, want %d", test.size, cap(*buffer), test.capacity)
+		}
+		pool.Put(buffer)
+	}
+}
+
+func TestBufferPoolDropsForeignBuffers(t *testing.T) {
+	pool := NewBufferPool(512, 4096)
+
+	// Buffers that do not fill a class exactly would come back too short for that class
+	for _, capacity := range []int{100, 600, 1500, 8192} {
+		foreign := make([]byte, 0, capacity)
+		pool.Put(&foreign)
+
+		for size := 1; size <= 4096; size *= 2 {
+			buffer := pool.Get(size)
+			if &(*buffer)[:1][0] == &foreign[:1][0] {
+				t.Fatalf("Get(%d) returned a foreign buffer of capacity %d", size, capacity)
+			}
+			pool.Put(buffer)
+		}
+	}
+
+	pool.Put(nil)
+}
+
+func TestBufferPoolNeverHandsOutHeldBuffers(t *testing.T) {
+	pool := NewBufferPool(512, 4096)
+
+	held := pool.Get(700)
+	released := pool.Get(700)
+	pool.Put(released)
+
+	// sync.Pool may drop buffers at any time, so only a held buffer handed out twice is a failure
+	for range 100 {
+		buffer := pool.Get(1000)
+		if &(*buffer)[0] == &(*held)[0] {
+			t.Fatalf("Get returned a buffer that is still held")
+		}
+		pool.Put(buffer)
+	}
+}
+
+func TestReleaseClientDataKeepsCapacity(t *testing.T) {
+	data := AcquireClientData()
+	data.Sequence = 7
+	data.Message = "pooled"
+	data.SensorReadings = append(data.SensorReadings, make([]float32, 64)...)
+	ReleaseClientData(data)
+
+	if data.Sequence != 0 || data.Message != "" || len(data.SensorReadings) != 0 {
+		t.Fatalf("released message was not reset: %v", data)
+	}
+	if cap(data.SensorReadings) < 64 {
+		t.Errorf("released message lost its readings capacity, %d left", cap(data.SensorReadings))
+	}
+
+	ReleaseClientData(nil)
+}
+
+func BenchmarkBufferPool(b *testing.B) {
+	for _, size := range []int{512, 64 * 1024, 1024 * 1024} {
+		b.Run(byteSizeName(size)+"/make", func(b *testing.B) {
+			reportGC(b, func() {
+				for b.Loop() {
+					buffer := make([]byte, size)
+					buffer[0] = 1
+				}
+			})
+		})
+		b.Run(byteSizeName(size)+"/pooled", func(b *testing.B) {
+			reportGC(b, func() {
+				for b.Loop() {
+					buffer := Buffers.Get(size)
+					(*buffer)[0] = 1
+					Buffers.Put(buffer)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkClientDataPool(b *testing.B) {
+	data := newTestData(benchReadings)
+
+	b.Run("new", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				batch := &pb.ClientData{}
+				batch.SensorReadings = append(batch.SensorReadings, data.SensorReadings...)
+			}
+		})
+	})
+	b.Run("pooled", func(b *testing.B) {
+		reportGC(b, func() {
+			for b.Loop() {
+				batch := AcquireClientData()
+				batch.SensorReadings = append(batch.SensorReadings, data.SensorReadings...)
+				ReleaseClientData(batch)
+			}
+		})
+	})
+}
+
+func byteSizeName(size int) string {
+	return ByteSize(size).String()
+}
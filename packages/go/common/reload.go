@@ -0,0 +1,276 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+const (
+	reloadableGroup  = `group:"reloadables"`
+	reloadDebounce   = 500 * time.Millisecond
+	reloadSourceHUP  = "sighup"
+	reloadSourceFile = "file"
+	maskedValue      = "******"
+)
+
+// ReloadEvent describes an applied configuration reload
+type ReloadEvent struct {
+	Previous *Config
+	Current  *Config
+	Changed  []string
+}
+
+// Reloadable is implemented by components that apply reloadable config fields at runtime
+type Reloadable interface {
+	Reload(event ReloadEvent)
+}
+
+// ProvideReloadable subscribes an already provided component to reload events
+func ProvideReloadable[T Reloadable]() fx.Option {
+	return fx.Provide(fx.Annotate(func(r T) Reloadable { return r }, fx.ResultTags(reloadableGroup)))
+}
+
+// ConfigReloader reloads the configuration on SIGHUP or config file change and publishes ReloadEvent to subscribers
+type ConfigReloader struct {
+	mu          sync.Mutex
+	file        string
+	logger      *zap.Logger
+	level       zap.AtomicLevel
+	current     *Config
+	reloadables []Reloadable
+}
+
+type configReloaderParams struct {
+	fx.In
+
+	Logger      *zap.Logger
+	Level       zap.AtomicLevel
+	Config      *Config
+	Lifecycle   fx.Lifecycle
+	Reloadables []Reloadable `group:"reloadables"`
+}
+
+type configChange struct {
+	key        string
+	index      []int
+	reloadable bool
+	previous   string
+	current    string
+}
+
+// NewConfigReloader creates the reloader and starts watching reload triggers with the application lifecycle
+func NewConfigReloader(params configReloaderParams) (*ConfigReloader, error) {
+	reloader := &ConfigReloader{
+		file:        params.Config.File,
+		logger:      params.Logger,
+		level:       params.Level,
+		current:     params.Config,
+		reloadables: params.Reloadables,
+	}
+
+	var watcher *fsnotify.Watcher
+	if params.Config.File != "" {
+		w, err := fsnotify.NewWatcher()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := w.Add(filepath.Dir(params.Config.File)); err != nil {
+			w.Close()
+			return nil, err
+		}
+
+		watcher = w
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+
+	params.Lifecycle.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			signal.Notify(signals, syscall.SIGHUP)
+
+			go reloader.watch(signals, watcher, done)
+
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			signal.Stop(signals)
+			close(done)
+
+			if watcher != nil {
+				return watcher.Close()
+			}
+
+			return nil
+		},
+	})
+
+	return reloader, nil
+}
+
+// Current returns the configuration currently in effect
+func (r *ConfigReloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.current
+}
+
+// Reload reads, validates and applies the configuration
+func (r *ConfigReloader) Reload(source string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.logger.Info("Reloading configuration", zap.String("source", source))
+
+	next, err := ReadConfig()
+	if err != nil {
+		r.logger.Error("Configuration reload rejected, keeping current configuration", zap.Error(err))
+		return
+	}
+
+	changes := diffConfig(r.current, next)
+
+	merged := *next
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	currentValue := reflect.ValueOf(r.current).Elem()
+	changed := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		if !change.reloadable {
+			r.logger.Warn("Config field cannot be reloaded, restart required to apply it",
+				zap.String("key", change.key),
+				zap.String("current", change.previous),
+				zap.String("requested", change.current))
+
+			mergedValue.FieldByIndex(change.index).Set(currentValue.FieldByIndex(change.index))
+
+			continue
+		}
+
+		r.logger.Info("Config field reloaded",
+			zap.String("key", change.key),
+			zap.String("previous", change.previous),
+			zap.String("current", change.current))
+
+		changed = append(changed, change.key)
+	}
+
+	if len(changed) == 0 {
+		r.logger.Info("Configuration reload finished without reloadable changes")
+		return
+	}
+
+	if err := r.level.UnmarshalText([]byte(merged.Log.Level)); err != nil {
+		r.logger.Error("Failed to apply log level", zap.Error(err))
+	}
+
+	event := ReloadEvent{Previous: r.current, Current: &merged, Changed: changed}
+	r.current = &merged
+
+	for _, reloadable := range r.reloadables {
+		reloadable.Reload(event)
+	}
+
+	r.logger.Info("Configuration reloaded", zap.Strings("changed", changed))
+}
+
+func (r *ConfigReloader) watch(signals <-chan os.Signal, watcher *fsnotify.Watcher, done <-chan struct{}) {
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		events = watcher.Events
+		errs = watcher.Errors
+	}
+
+	debounce := time.NewTimer(reloadDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-signals:
+			r.Reload(reloadSourceHUP)
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+
+			if filepath.Clean(event.Name) == filepath.Clean(r.file) && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				debounce.Reset(reloadDebounce)
+			}
+		case <-debounce.C:
+			r.Reload(reloadSourceFile)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+
+			r.logger.Error("Config file watcher error", zap.Error(err))
+		}
+	}
+}
+
+func diffConfig(previous, current *Config) []configChange {
+	var changes []configChange
+	collectChanges(reflect.ValueOf(previous).Elem(), reflect.ValueOf(current).Elem(), nil, "", &changes)
+
+	return changes
+}
+
+func collectChanges(previous, current reflect.Value, index []int, prefix string, changes *[]configChange) {
+	for i := 0; i < previous.NumField(); i++ {
+		field := previous.Type().Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = field.Name
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			collectChanges(previous.Field(i), current.Field(i), fieldIndex, key, changes)
+			continue
+		}
+
+		if reflect.DeepEqual(previous.Field(i).Interface(), current.Field(i).Interface()) {
+			continue
+		}
+
+		change := configChange{
+			key:        key,
+			index:      fieldIndex,
+			reloadable: field.Tag.Get("reload") == "true",
+			previous:   fmt.Sprint(previous.Field(i).Interface()),
+			current:    fmt.Sprint(current.Field(i).Interface()),
+		}
+		if field.Tag.Get("secret") == "true" {
+			change.previous, change.current = maskedValue, maskedValue
+		}
+
+		*changes = append(*changes, change)
+	}
+}
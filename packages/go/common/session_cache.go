@@ -0,0 +1,185 @@
+package common
+
+import (
+	"container/list"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// SessionCache is a tls.ClientSessionCache keeping the most recently used session tickets.
+// With a file path the tickets are written to the file on every change and loaded on creation,
+// so resumption and 0-RTT survive a client restart.
+type SessionCache struct {
+	mu       sync.Mutex
+	path     string
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	logger   *zap.Logger
+}
+
+type sessionCacheEntry struct {
+	Key    string `json:"key"`
+	Ticket []byte `json:"ticket"`
+	State  []byte `json:"state"`
+}
+
+// NewSessionCache creates a cache holding up to capacity sessions, loading the file at path when set.
+// An unreadable file is logged and replaced, as it only costs a full handshake.
+func NewSessionCache(path string, capacity int, logger *zap.Logger) *SessionCache {
+	cache := &SessionCache{
+		path:     path,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		logger:   logger,
+	}
+
+	if path != "" {
+		if err := cache.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Ignoring unreadable session cache", zap.String("file", path), zap.Error(err))
+		}
+	}
+
+	return cache
+}
+
+// Get returns the session stored for a server
+func (c *SessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[sessionKey]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	entry := element.Value.(*sessionCacheEntry)
+	state, err := tls.ParseSessionState(entry.State)
+	if err != nil {
+		c.remove(element)
+		return nil, false
+	}
+
+	session, err := tls.NewResumptionState(entry.Ticket, state)
+	if err != nil {
+		c.remove(element)
+		return nil, false
+	}
+
+	return session, true
+}
+
+// Put stores the session of a server, or removes it when cs is nil
+func (c *SessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cs == nil {
+		if element, ok := c.entries[sessionKey]; ok {
+			c.remove(element)
+			c.save()
+		}
+		return
+	}
+
+	ticket, state, err := cs.ResumptionState()
+	if err != nil || state == nil {
+		return
+	}
+
+	encoded, err := state.Bytes()
+	if err != nil {
+		c.logger.Debug("Failed to encode session state", zap.Error(err))
+		return
+	}
+
+	entry := &sessionCacheEntry{Key: sessionKey, Ticket: ticket, State: encoded}
+	if element, ok := c.entries[sessionKey]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+	} else {
+		c.entries[sessionKey] = c.order.PushFront(entry)
+	}
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+
+	c.save()
+}
+
+// remove drops an entry. Callers must hold c.mu.
+func (c *SessionCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*sessionCacheEntry).Key)
+	c.order.Remove(element)
+}
+
+func (c *SessionCache) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return err
+	}
+
+	var entries []*sessionCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	// Entries are stored most recent first
+	for _, entry := range entries {
+		if len(c.entries) >= c.capacity {
+			break
+		}
+		c.entries[entry.Key] = c.order.PushBack(entry)
+	}
+
+	return nil
+}
+
+// save replaces the cache file atomically. Callers must hold c.mu.
+func (c *SessionCache) save() {
+	if c.path == "" {
+		return
+	}
+
+	entries := make([]*sessionCacheEntry, 0, c.order.Len())
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entries = append(entries, element.Value.(*sessionCacheEntry))
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		c.logger.Error("Failed to encode session cache", zap.Error(err))
+		return
+	}
+
+	// Tickets grant resumption of the client's sessions, so the file is private
+	temp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		c.logger.Error("Failed to write session cache", zap.String("file", c.path), zap.Error(err))
+		return
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		c.logger.Error("Failed to write session cache", zap.String("file", c.path), zap.Error(err))
+		return
+	}
+	if err := temp.Close(); err != nil {
+		c.logger.Error("Failed to write session cache", zap.String("file", c.path), zap.Error(err))
+		return
+	}
+
+	if err := os.Rename(temp.Name(), c.path); err != nil {
+		c.logger.Error("Failed to write session cache", zap.String("file", c.path), zap.Error(err))
+	}
+}
@@ -2,28 +2,123 @@ package common
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"os"
+
+	"go.uber.org/fx"
+	"go.uber.org/zap"
 )
 
-type Tls struct {
-	Config *tls.Config
+// NextProto is the ALPN protocol negotiated between suction clients and servers
+const NextProto = "suction-quic"
+
+// ServerTLS holds the TLS configuration used by the QUIC listener
+type ServerTLS struct {
+	Config      *tls.Config
+	Certificate *CertificateReloader
+}
+
+// ClientTLS holds the TLS configuration used to dial the QUIC server
+type ClientTLS struct {
+	Config      *tls.Config
+	Certificate *CertificateReloader
+}
+
+// NewServerTLSConfig loads the server certificate and key, serving renewed files without a restart.
+// Session ticket keys are set by the server once they are loaded from the shared store.
+func NewServerTLSConfig(config *Config, logger *zap.Logger, metrics *Metrics, lifecycle fx.Lifecycle) (*ServerTLS, error) {
+	tlsConfig := config.Server.TLS
+	if tlsConfig.CertFile == "" {
+		return nil, errors.New("\"server.tls.cert_file\" is required")
+	}
+	if tlsConfig.KeyFile == "" {
+		return nil, errors.New("\"server.tls.key_file\" is required")
+	}
+
+	certificate, err := NewCertificateReloader("server", tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ExpiryWarning, logger, metrics, lifecycle)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs, err := loadCertPool(tlsConfig.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth := tls.NoClientCert
+	if clientCAs != nil {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &ServerTLS{
+		Config: &tls.Config{
+			GetCertificate: certificate.GetCertificate,
+			ClientCAs:      clientCAs,
+			ClientAuth:     clientAuth,
+			NextProtos:     []string{NextProto},
+			MinVersion:     tls.VersionTLS13,
+
+			SessionTicketsDisabled: config.Server.Resumption.Disabled,
+		},
+		Certificate: certificate,
+	}, nil
 }
 
-func NewTLSConfig(config *Config) (*Tls, error) {
-	if config.IsLocal() {
-		tlsCert, err := tls.LoadX509KeyPair("../../samples/server.crt", "../../samples/server.key")
+// MutualTLS reports whether clients must present a certificate signed by the client CA
+func (s *ServerTLS) MutualTLS() bool {
+	return s.Config.ClientAuth == tls.RequireAndVerifyClientCert
+}
+
+// NewClientTLSConfig builds the client configuration verifying the server against the configured CA bundle,
+// or the system roots when no bundle is configured. A configured client certificate is presented for mutual TLS.
+func NewClientTLSConfig(config *Config, logger *zap.Logger, metrics *Metrics, lifecycle fx.Lifecycle) (*ClientTLS, error) {
+	tlsConfig := config.Client.TLS
+
+	rootCAs, err := loadCertPool(tlsConfig.CAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	clientTLS := &ClientTLS{Config: &tls.Config{
+		RootCAs:            rootCAs,
+		ServerName:         tlsConfig.ServerName,
+		NextProtos:         []string{NextProto},
+		MinVersion:         tls.VersionTLS13,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+	}}
+
+	if resumption := config.Client.Resumption; !resumption.Disabled {
+		clientTLS.Config.ClientSessionCache = NewSessionCache(resumption.SessionCacheFile, resumption.SessionCacheSize, logger)
+	}
+
+	if tlsConfig.CertFile != "" {
+		certificate, err := NewCertificateReloader("client", tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ExpiryWarning, logger, metrics, lifecycle)
 		if err != nil {
 			return nil, err
 		}
 
-		return &Tls{Config: &tls.Config{
-			Certificates:       []tls.Certificate{tlsCert},
-			NextProtos:         []string{"suction-quic"},
-			InsecureSkipVerify: true,
-		}}, nil
+		clientTLS.Config.GetClientCertificate = certificate.GetClientCertificate
+		clientTLS.Certificate = certificate
 	}
 
-	// TODO: production environment support
+	return clientTLS, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("Could not read CA bundle: " + err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("No certificates found in CA bundle " + path)
+	}
 
-	return nil, errors.New("support only local environment")
+	return pool, nil
 }
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+
+	"github.com/dustin/go-humanize"
+)
+
+// ByteSize is a size in bytes that accepts human readable values such as "8KiB" or "6MiB"
+type ByteSize uint64
+
+// UnmarshalText parses a human readable byte size
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := humanize.ParseBytes(string(text))
+	if err != nil {
+		return errors.New("Invalid byte size " + string(text) + ": " + err.Error())
+	}
+
+	*b = ByteSize(size)
+
+	return nil
+}
+
+// MarshalText formats the size with IEC units
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+func (b ByteSize) String() string {
+	return humanize.IBytes(uint64(b))
+}
+
+// Int returns the size as int
+func (b ByteSize) Int() int {
+	return int(b)
+}
+
+// Uint64 returns the size as uint64
+func (b ByteSize) Uint64() uint64 {
+	return uint64(b)
+}
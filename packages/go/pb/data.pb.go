@@ -26,6 +26,7 @@ type ClientData struct {
 	Timestamp      int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Message        string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	SensorReadings []float32              `protobuf:"fixed32,3,rep,packed,name=sensor_readings,json=sensorReadings,proto3" json:"sensor_readings,omitempty"`
+	Sequence       uint64                 `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"`
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
@@ -81,17 +82,25 @@ func (x *ClientData) GetSensorReadings() []float32 {
 	return nil
 }
 
+func (x *ClientData) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
 var File_data_proto protoreflect.FileDescriptor
 
 const file_data_proto_rawDesc = "" +
 	"\n" +
 	"\n" +
-	"data.proto\x12\x02pb\"m\n" +
+	"data.proto\x12\x02pb\"\x89\x01\n" +
 	"\n" +
 	"ClientData\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12'\n" +
-	"\x0fsensor_readings\x18\x03 \x03(\x02R\x0esensorReadingsB\x06Z\x04.;pbb\x06proto3"
+	"\x0fsensor_readings\x18\x03 \x03(\x02R\x0esensorReadings\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x04R\bsequenceB\x06Z\x04.;pbb\x06proto3"
 
 var (
 	file_data_proto_rawDescOnce sync.Once
@@ -0,0 +1,1156 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        v3.21.12
+// source: protocol.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AuthStatus int32
+
+const (
+	AuthStatus_AUTH_STATUS_OK     AuthStatus = 0
+	AuthStatus_AUTH_STATUS_DENIED AuthStatus = 1
+)
+
+// Enum value maps for AuthStatus.
+var (
+	AuthStatus_name = map[int32]string{
+		0: "AUTH_STATUS_OK",
+		1: "AUTH_STATUS_DENIED",
+	}
+	AuthStatus_value = map[string]int32{
+		"AUTH_STATUS_OK":     0,
+		"AUTH_STATUS_DENIED": 1,
+	}
+)
+
+func (x AuthStatus) Enum() *AuthStatus {
+	p := new(AuthStatus)
+	*p = x
+	return p
+}
+
+func (x AuthStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AuthStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_protocol_proto_enumTypes[0].Descriptor()
+}
+
+func (AuthStatus) Type() protoreflect.EnumType {
+	return &file_protocol_proto_enumTypes[0]
+}
+
+func (x AuthStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AuthStatus.Descriptor instead.
+func (AuthStatus) EnumDescriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{0}
+}
+
+type AckStatus int32
+
+const (
+	AckStatus_ACK_STATUS_OK             AckStatus = 0
+	AckStatus_ACK_STATUS_ERROR          AckStatus = 1
+	AckStatus_ACK_STATUS_THROTTLED      AckStatus = 2
+	AckStatus_ACK_STATUS_QUOTA_EXCEEDED AckStatus = 3
+)
+
+// Enum value maps for AckStatus.
+var (
+	AckStatus_name = map[int32]string{
+		0: "ACK_STATUS_OK",
+		1: "ACK_STATUS_ERROR",
+		2: "ACK_STATUS_THROTTLED",
+		3: "ACK_STATUS_QUOTA_EXCEEDED",
+	}
+	AckStatus_value = map[string]int32{
+		"ACK_STATUS_OK":             0,
+		"ACK_STATUS_ERROR":          1,
+		"ACK_STATUS_THROTTLED":      2,
+		"ACK_STATUS_QUOTA_EXCEEDED": 3,
+	}
+)
+
+func (x AckStatus) Enum() *AckStatus {
+	p := new(AckStatus)
+	*p = x
+	return p
+}
+
+func (x AckStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AckStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_protocol_proto_enumTypes[1].Descriptor()
+}
+
+func (AckStatus) Type() protoreflect.EnumType {
+	return &file_protocol_proto_enumTypes[1]
+}
+
+func (x AckStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AckStatus.Descriptor instead.
+func (AckStatus) EnumDescriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{1}
+}
+
+type ControlCommand int32
+
+const (
+	ControlCommand_CONTROL_COMMAND_UNSPECIFIED ControlCommand = 0
+	ControlCommand_CONTROL_COMMAND_SLOW_DOWN   ControlCommand = 1
+	ControlCommand_CONTROL_COMMAND_FLUSH       ControlCommand = 2
+	ControlCommand_CONTROL_COMMAND_RECONNECT   ControlCommand = 3
+)
+
+// Enum value maps for ControlCommand.
+var (
+	ControlCommand_name = map[int32]string{
+		0: "CONTROL_COMMAND_UNSPECIFIED",
+		1: "CONTROL_COMMAND_SLOW_DOWN",
+		2: "CONTROL_COMMAND_FLUSH",
+		3: "CONTROL_COMMAND_RECONNECT",
+	}
+	ControlCommand_value = map[string]int32{
+		"CONTROL_COMMAND_UNSPECIFIED": 0,
+		"CONTROL_COMMAND_SLOW_DOWN":   1,
+		"CONTROL_COMMAND_FLUSH":       2,
+		"CONTROL_COMMAND_RECONNECT":   3,
+	}
+)
+
+func (x ControlCommand) Enum() *ControlCommand {
+	p := new(ControlCommand)
+	*p = x
+	return p
+}
+
+func (x ControlCommand) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ControlCommand) Descriptor() protoreflect.EnumDescriptor {
+	return file_protocol_proto_enumTypes[2].Descriptor()
+}
+
+func (ControlCommand) Type() protoreflect.EnumType {
+	return &file_protocol_proto_enumTypes[2]
+}
+
+func (x ControlCommand) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ControlCommand.Descriptor instead.
+func (ControlCommand) EnumDescriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{2}
+}
+
+type AuthRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Credential:
+	//
+	//	*AuthRequest_ApiKey
+	//	*AuthRequest_Token
+	Credential    isAuthRequest_Credential `protobuf_oneof:"credential"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthRequest) Reset() {
+	*x = AuthRequest{}
+	mi := &file_protocol_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthRequest) ProtoMessage() {}
+
+func (x *AuthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthRequest.ProtoReflect.Descriptor instead.
+func (*AuthRequest) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AuthRequest) GetCredential() isAuthRequest_Credential {
+	if x != nil {
+		return x.Credential
+	}
+	return nil
+}
+
+func (x *AuthRequest) GetApiKey() string {
+	if x != nil {
+		if x, ok := x.Credential.(*AuthRequest_ApiKey); ok {
+			return x.ApiKey
+		}
+	}
+	return ""
+}
+
+func (x *AuthRequest) GetToken() string {
+	if x != nil {
+		if x, ok := x.Credential.(*AuthRequest_Token); ok {
+			return x.Token
+		}
+	}
+	return ""
+}
+
+type isAuthRequest_Credential interface {
+	isAuthRequest_Credential()
+}
+
+type AuthRequest_ApiKey struct {
+	ApiKey string `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3,oneof"`
+}
+
+type AuthRequest_Token struct {
+	Token string `protobuf:"bytes,2,opt,name=token,proto3,oneof"`
+}
+
+func (*AuthRequest_ApiKey) isAuthRequest_Credential() {}
+
+func (*AuthRequest_Token) isAuthRequest_Credential() {}
+
+type AuthResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        AuthStatus             `protobuf:"varint,1,opt,name=status,proto3,enum=pb.AuthStatus" json:"status,omitempty"`
+	ClientId      string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthResponse) Reset() {
+	*x = AuthResponse{}
+	mi := &file_protocol_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthResponse) ProtoMessage() {}
+
+func (x *AuthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
+func (*AuthResponse) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AuthResponse) GetStatus() AuthStatus {
+	if x != nil {
+		return x.Status
+	}
+	return AuthStatus_AUTH_STATUS_OK
+}
+
+func (x *AuthResponse) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *AuthResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Ack settles a batch. A throttled or over-quota batch may be sent again once retry_after_ms passed;
+// 0 leaves the wait to the client.
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Status        AckStatus              `protobuf:"varint,2,opt,name=status,proto3,enum=pb.AckStatus" json:"status,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	RetryAfterMs  uint32                 `protobuf:"varint,4,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_protocol_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Ack) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *Ack) GetStatus() AckStatus {
+	if x != nil {
+		return x.Status
+	}
+	return AckStatus_ACK_STATUS_OK
+}
+
+func (x *Ack) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Ack) GetRetryAfterMs() uint32 {
+	if x != nil {
+		return x.RetryAfterMs
+	}
+	return 0
+}
+
+type Drain struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Endpoint      string                 `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Drain) Reset() {
+	*x = Drain{}
+	mi := &file_protocol_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Drain) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Drain) ProtoMessage() {}
+
+func (x *Drain) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Drain.ProtoReflect.Descriptor instead.
+func (*Drain) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Drain) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Drain) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Error reports a failure the client should know about without the stream or connection ending.
+// code is one of the application error codes.
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          uint64                 `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Sequence      uint64                 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_protocol_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Error) GetCode() uint64 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Error) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+// Control asks the client to change how it sends. A slow down raises the flush interval to
+// flush_interval_ms for duration_ms; a reconnect moves the client to endpoint when set.
+type Control struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Command         ControlCommand         `protobuf:"varint,1,opt,name=command,proto3,enum=pb.ControlCommand" json:"command,omitempty"`
+	FlushIntervalMs uint32                 `protobuf:"varint,2,opt,name=flush_interval_ms,json=flushIntervalMs,proto3" json:"flush_interval_ms,omitempty"`
+	DurationMs      uint32                 `protobuf:"varint,3,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`
+	Endpoint        string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Reason          string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Control) Reset() {
+	*x = Control{}
+	mi := &file_protocol_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Control) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Control) ProtoMessage() {}
+
+func (x *Control) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Control.ProtoReflect.Descriptor instead.
+func (*Control) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Control) GetCommand() ControlCommand {
+	if x != nil {
+		return x.Command
+	}
+	return ControlCommand_CONTROL_COMMAND_UNSPECIFIED
+}
+
+func (x *Control) GetFlushIntervalMs() uint32 {
+	if x != nil {
+		return x.FlushIntervalMs
+	}
+	return 0
+}
+
+func (x *Control) GetDurationMs() uint32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *Control) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Control) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// RemoteConfig carries the settings the server pushes to a client on its control stream. Zero values
+// leave the local setting in place. source names the document the settings came from.
+type RemoteConfig struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Version         uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Source          string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	FlushIntervalMs uint32                 `protobuf:"varint,3,opt,name=flush_interval_ms,json=flushIntervalMs,proto3" json:"flush_interval_ms,omitempty"`
+	PoolMaxItems    uint32                 `protobuf:"varint,4,opt,name=pool_max_items,json=poolMaxItems,proto3" json:"pool_max_items,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RemoteConfig) Reset() {
+	*x = RemoteConfig{}
+	mi := &file_protocol_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoteConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoteConfig) ProtoMessage() {}
+
+func (x *RemoteConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoteConfig.ProtoReflect.Descriptor instead.
+func (*RemoteConfig) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RemoteConfig) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RemoteConfig) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *RemoteConfig) GetFlushIntervalMs() uint32 {
+	if x != nil {
+		return x.FlushIntervalMs
+	}
+	return 0
+}
+
+func (x *RemoteConfig) GetPoolMaxItems() uint32 {
+	if x != nil {
+		return x.PoolMaxItems
+	}
+	return 0
+}
+
+type RemoteConfigAck struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       uint64                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Applied       bool                   `protobuf:"varint,2,opt,name=applied,proto3" json:"applied,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoteConfigAck) Reset() {
+	*x = RemoteConfigAck{}
+	mi := &file_protocol_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoteConfigAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoteConfigAck) ProtoMessage() {}
+
+func (x *RemoteConfigAck) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoteConfigAck.ProtoReflect.Descriptor instead.
+func (*RemoteConfigAck) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RemoteConfigAck) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *RemoteConfigAck) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *RemoteConfigAck) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Backpressure reports the load of the server ingest pipeline in percent and the rate in bytes per
+// second of data frames the client should stay under while it lasts. A rate of 0 lifts the limit.
+type Backpressure struct {
+	state                   protoimpl.MessageState `protogen:"open.v1"`
+	LoadPercent             uint32                 `protobuf:"varint,1,opt,name=load_percent,json=loadPercent,proto3" json:"load_percent,omitempty"`
+	SuggestedBytesPerSecond uint64                 `protobuf:"varint,2,opt,name=suggested_bytes_per_second,json=suggestedBytesPerSecond,proto3" json:"suggested_bytes_per_second,omitempty"`
+	unknownFields           protoimpl.UnknownFields
+	sizeCache               protoimpl.SizeCache
+}
+
+func (x *Backpressure) Reset() {
+	*x = Backpressure{}
+	mi := &file_protocol_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Backpressure) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Backpressure) ProtoMessage() {}
+
+func (x *Backpressure) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Backpressure.ProtoReflect.Descriptor instead.
+func (*Backpressure) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Backpressure) GetLoadPercent() uint32 {
+	if x != nil {
+		return x.LoadPercent
+	}
+	return 0
+}
+
+func (x *Backpressure) GetSuggestedBytesPerSecond() uint64 {
+	if x != nil {
+		return x.SuggestedBytesPerSecond
+	}
+	return 0
+}
+
+// ClientStatus is the health report a client sends every heartbeat interval on its status stream.
+// Times are unix milliseconds; last_error_at is 0 while the client had no error.
+type ClientStatus struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Version         string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	StartedAt       int64                  `protobuf:"varint,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	SentAt          int64                  `protobuf:"varint,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	UptimeSeconds   uint64                 `protobuf:"varint,4,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	PoolItems       uint32                 `protobuf:"varint,5,opt,name=pool_items,json=poolItems,proto3" json:"pool_items,omitempty"`
+	PoolMaxItems    uint32                 `protobuf:"varint,6,opt,name=pool_max_items,json=poolMaxItems,proto3" json:"pool_max_items,omitempty"`
+	SpoolBytes      uint64                 `protobuf:"varint,7,opt,name=spool_bytes,json=spoolBytes,proto3" json:"spool_bytes,omitempty"`
+	DroppedItems    uint64                 `protobuf:"varint,8,opt,name=dropped_items,json=droppedItems,proto3" json:"dropped_items,omitempty"`
+	RejectedBatches uint64                 `protobuf:"varint,9,opt,name=rejected_batches,json=rejectedBatches,proto3" json:"rejected_batches,omitempty"`
+	LastError       string                 `protobuf:"bytes,10,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	LastErrorAt     int64                  `protobuf:"varint,11,opt,name=last_error_at,json=lastErrorAt,proto3" json:"last_error_at,omitempty"`
+	ConfigVersion   uint64                 `protobuf:"varint,12,opt,name=config_version,json=configVersion,proto3" json:"config_version,omitempty"`
+	Host            *HostStatus            `protobuf:"bytes,13,opt,name=host,proto3" json:"host,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ClientStatus) Reset() {
+	*x = ClientStatus{}
+	mi := &file_protocol_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientStatus) ProtoMessage() {}
+
+func (x *ClientStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientStatus.ProtoReflect.Descriptor instead.
+func (*ClientStatus) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ClientStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ClientStatus) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetSentAt() int64 {
+	if x != nil {
+		return x.SentAt
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetUptimeSeconds() uint64 {
+	if x != nil {
+		return x.UptimeSeconds
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetPoolItems() uint32 {
+	if x != nil {
+		return x.PoolItems
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetPoolMaxItems() uint32 {
+	if x != nil {
+		return x.PoolMaxItems
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetSpoolBytes() uint64 {
+	if x != nil {
+		return x.SpoolBytes
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetDroppedItems() uint64 {
+	if x != nil {
+		return x.DroppedItems
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetRejectedBatches() uint64 {
+	if x != nil {
+		return x.RejectedBatches
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *ClientStatus) GetLastErrorAt() int64 {
+	if x != nil {
+		return x.LastErrorAt
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetConfigVersion() uint64 {
+	if x != nil {
+		return x.ConfigVersion
+	}
+	return 0
+}
+
+func (x *ClientStatus) GetHost() *HostStatus {
+	if x != nil {
+		return x.Host
+	}
+	return nil
+}
+
+// HostStatus holds the figures of the machine a client runs on. cpu_percent is the usage of all
+// CPUs since the previous report; disk figures are for the file system holding disk_path.
+type HostStatus struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Hostname         string                 `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	CpuPercent       float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryTotalBytes uint64                 `protobuf:"varint,3,opt,name=memory_total_bytes,json=memoryTotalBytes,proto3" json:"memory_total_bytes,omitempty"`
+	MemoryUsedBytes  uint64                 `protobuf:"varint,4,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`
+	DiskPath         string                 `protobuf:"bytes,5,opt,name=disk_path,json=diskPath,proto3" json:"disk_path,omitempty"`
+	DiskTotalBytes   uint64                 `protobuf:"varint,6,opt,name=disk_total_bytes,json=diskTotalBytes,proto3" json:"disk_total_bytes,omitempty"`
+	DiskUsedBytes    uint64                 `protobuf:"varint,7,opt,name=disk_used_bytes,json=diskUsedBytes,proto3" json:"disk_used_bytes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *HostStatus) Reset() {
+	*x = HostStatus{}
+	mi := &file_protocol_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HostStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HostStatus) ProtoMessage() {}
+
+func (x *HostStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_protocol_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HostStatus.ProtoReflect.Descriptor instead.
+func (*HostStatus) Descriptor() ([]byte, []int) {
+	return file_protocol_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HostStatus) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *HostStatus) GetCpuPercent() float64 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *HostStatus) GetMemoryTotalBytes() uint64 {
+	if x != nil {
+		return x.MemoryTotalBytes
+	}
+	return 0
+}
+
+func (x *HostStatus) GetMemoryUsedBytes() uint64 {
+	if x != nil {
+		return x.MemoryUsedBytes
+	}
+	return 0
+}
+
+func (x *HostStatus) GetDiskPath() string {
+	if x != nil {
+		return x.DiskPath
+	}
+	return ""
+}
+
+func (x *HostStatus) GetDiskTotalBytes() uint64 {
+	if x != nil {
+		return x.DiskTotalBytes
+	}
+	return 0
+}
+
+func (x *HostStatus) GetDiskUsedBytes() uint64 {
+	if x != nil {
+		return x.DiskUsedBytes
+	}
+	return 0
+}
+
+var File_protocol_proto protoreflect.FileDescriptor
+
+const file_protocol_proto_rawDesc = "" +
+	"\n" +
+	"\x0eprotocol.proto\x12\x02pb\"N\n" +
+	"\vAuthRequest\x12\x19\n" +
+	"\aapi_key\x18\x01 \x01(\tH\x00R\x06apiKey\x12\x16\n" +
+	"\x05token\x18\x02 \x01(\tH\x00R\x05tokenB\f\n" +
+	"\n" +
+	"credential\"m\n" +
+	"\fAuthResponse\x12&\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x0e.pb.AuthStatusR\x06status\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"\x88\x01\n" +
+	"\x03Ack\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12%\n" +
+	"\x06status\x18\x02 \x01(\x0e2\r.pb.AckStatusR\x06status\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12$\n" +
+	"\x0eretry_after_ms\x18\x04 \x01(\rR\fretryAfterMs\";\n" +
+	"\x05Drain\x12\x1a\n" +
+	"\bendpoint\x18\x01 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"Q\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x04R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\bsequence\x18\x03 \x01(\x04R\bsequence\"\xb8\x01\n" +
+	"\aControl\x12,\n" +
+	"\acommand\x18\x01 \x01(\x0e2\x12.pb.ControlCommandR\acommand\x12*\n" +
+	"\x11flush_interval_ms\x18\x02 \x01(\rR\x0fflushIntervalMs\x12\x1f\n" +
+	"\vduration_ms\x18\x03 \x01(\rR\n" +
+	"durationMs\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"\x92\x01\n" +
+	"\fRemoteConfig\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12*\n" +
+	"\x11flush_interval_ms\x18\x03 \x01(\rR\x0fflushIntervalMs\x12$\n" +
+	"\x0epool_max_items\x18\x04 \x01(\rR\fpoolMaxItems\"_\n" +
+	"\x0fRemoteConfigAck\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x04R\aversion\x12\x18\n" +
+	"\aapplied\x18\x02 \x01(\bR\aapplied\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"n\n" +
+	"\fBackpressure\x12!\n" +
+	"\fload_percent\x18\x01 \x01(\rR\vloadPercent\x12;\n" +
+	"\x1asuggested_bytes_per_second\x18\x02 \x01(\x04R\x17suggestedBytesPerSecond\"\xcb\x03\n" +
+	"\fClientStatus\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x02 \x01(\x03R\tstartedAt\x12\x17\n" +
+	"\asent_at\x18\x03 \x01(\x03R\x06sentAt\x12%\n" +
+	"\x0euptime_seconds\x18\x04 \x01(\x04R\ruptimeSeconds\x12\x1d\n" +
+	"\n" +
+	"pool_items\x18\x05 \x01(\rR\tpoolItems\x12$\n" +
+	"\x0epool_max_items\x18\x06 \x01(\rR\fpoolMaxItems\x12\x1f\n" +
+	"\vspool_bytes\x18\a \x01(\x04R\n" +
+	"spoolBytes\x12#\n" +
+	"\rdropped_items\x18\b \x01(\x04R\fdroppedItems\x12)\n" +
+	"\x10rejected_batches\x18\t \x01(\x04R\x0frejectedBatches\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\n" +
+	" \x01(\tR\tlastError\x12\"\n" +
+	"\rlast_error_at\x18\v \x01(\x03R\vlastErrorAt\x12%\n" +
+	"\x0econfig_version\x18\f \x01(\x04R\rconfigVersion\x12\"\n" +
+	"\x04host\x18\r \x01(\v2\x0e.pb.HostStatusR\x04host\"\x92\x02\n" +
+	"\n" +
+	"HostStatus\x12\x1a\n" +
+	"\bhostname\x18\x01 \x01(\tR\bhostname\x12\x1f\n" +
+	"\vcpu_percent\x18\x02 \x01(\x01R\n" +
+	"cpuPercent\x12,\n" +
+	"\x12memory_total_bytes\x18\x03 \x01(\x04R\x10memoryTotalBytes\x12*\n" +
+	"\x11memory_used_bytes\x18\x04 \x01(\x04R\x0fmemoryUsedBytes\x12\x1b\n" +
+	"\tdisk_path\x18\x05 \x01(\tR\bdiskPath\x12(\n" +
+	"\x10disk_total_bytes\x18\x06 \x01(\x04R\x0ediskTotalBytes\x12&\n" +
+	"\x0fdisk_used_bytes\x18\a \x01(\x04R\rdiskUsedBytes*8\n" +
+	"\n" +
+	"AuthStatus\x12\x12\n" +
+	"\x0eAUTH_STATUS_OK\x10\x00\x12\x16\n" +
+	"\x12AUTH_STATUS_DENIED\x10\x01*m\n" +
+	"\tAckStatus\x12\x11\n" +
+	"\rACK_STATUS_OK\x10\x00\x12\x14\n" +
+	"\x10ACK_STATUS_ERROR\x10\x01\x12\x18\n" +
+	"\x14ACK_STATUS_THROTTLED\x10\x02\x12\x1d\n" +
+	"\x19ACK_STATUS_QUOTA_EXCEEDED\x10\x03*\x8a\x01\n" +
+	"\x0eControlCommand\x12\x1f\n" +
+	"\x1bCONTROL_COMMAND_UNSPECIFIED\x10\x00\x12\x1d\n" +
+	"\x19CONTROL_COMMAND_SLOW_DOWN\x10\x01\x12\x19\n" +
+	"\x15CONTROL_COMMAND_FLUSH\x10\x02\x12\x1d\n" +
+	"\x19CONTROL_COMMAND_RECONNECT\x10\x03B\x06Z\x04.;pbb\x06proto3"
+
+var (
+	file_protocol_proto_rawDescOnce sync.Once
+	file_protocol_proto_rawDescData []byte
+)
+
+func file_protocol_proto_rawDescGZIP() []byte {
+	file_protocol_proto_rawDescOnce.Do(func() {
+		file_protocol_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protocol_proto_rawDesc), len(file_protocol_proto_rawDesc)))
+	})
+	return file_protocol_proto_rawDescData
+}
+
+var file_protocol_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_protocol_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_protocol_proto_goTypes = []any{
+	(AuthStatus)(0),         // 0: pb.AuthStatus
+	(AckStatus)(0),          // 1: pb.AckStatus
+	(ControlCommand)(0),     // 2: pb.ControlCommand
+	(*AuthRequest)(nil),     // 3: pb.AuthRequest
+	(*AuthResponse)(nil),    // 4: pb.AuthResponse
+	(*Ack)(nil),             // 5: pb.Ack
+	(*Drain)(nil),           // 6: pb.Drain
+	(*Error)(nil),           // 7: pb.Error
+	(*Control)(nil),         // 8: pb.Control
+	(*RemoteConfig)(nil),    // 9: pb.RemoteConfig
+	(*RemoteConfigAck)(nil), // 10: pb.RemoteConfigAck
+	(*Backpressure)(nil),    // 11: pb.Backpressure
+	(*ClientStatus)(nil),    // 12: pb.ClientStatus
+	(*HostStatus)(nil),      // 13: pb.HostStatus
+}
+var file_protocol_proto_depIdxs = []int32{
+	0,  // 0: pb.AuthResponse.status:type_name -> pb.AuthStatus
+	1,  // 1: pb.Ack.status:type_name -> pb.AckStatus
+	2,  // 2: pb.Control.command:type_name -> pb.ControlCommand
+	13, // 3: pb.ClientStatus.host:type_name -> pb.HostStatus
+	4,  // [4:4] is the sub-list for method output_type
+	4,  // [4:4] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_protocol_proto_init() }
+func file_protocol_proto_init() {
+	if File_protocol_proto != nil {
+		return
+	}
+	file_protocol_proto_msgTypes[0].OneofWrappers = []any{
+		(*AuthRequest_ApiKey)(nil),
+		(*AuthRequest_Token)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protocol_proto_rawDesc), len(file_protocol_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protocol_proto_goTypes,
+		DependencyIndexes: file_protocol_proto_depIdxs,
+		EnumInfos:         file_protocol_proto_enumTypes,
+		MessageInfos:      file_protocol_proto_msgTypes,
+	}.Build()
+	File_protocol_proto = out.File
+	file_protocol_proto_goTypes = nil
+	file_protocol_proto_depIdxs = nil
+}